@@ -2,6 +2,12 @@
 
 package inj
 
+import (
+	"context"
+	"flag"
+	"time"
+)
+
 //////////////////////////////////////////////
 // Interface definitions
 //////////////////////////////////////////////
@@ -9,9 +15,20 @@ package inj
 // A Grapher is anything that can represent an application graph
 type Grapher interface {
 	Provide(inputs ...interface{}) error
+	ProvideContext(ctx context.Context, inputs ...interface{}) error
 	Inject(fn interface{}, args ...interface{})
 	Assert() (valid bool, errors []string)
 	AddDatasource(...interface{}) error
+	InjectMethods(obj interface{}, methods ...string) error
+	Configure(obj interface{}, fields ...FieldSpec) error
+	AddConverter(fn interface{}) error
+	SetReadTimeout(timeout time.Duration)
+	SetLossyConversion(allow bool)
+	Manifest() *Manifest
+	Install(modules ...*Module) error
+	Decorate(fn interface{}) error
+	AddObserver(o Observer)
+	RegisterFlags(fs *flag.FlagSet)
 }
 
 //////////////////////////////////////////////
@@ -41,17 +58,37 @@ func SetGrapher(g Grapher) {
 // As explained in the main documentation (https://godoc.org/github.com/yourheropaul/inj),
 // a graph consists of what is essentially a map of types to values. If the same type is
 // provided twice with different values, the *last* value will be stored in the graph.
+//
+// Unmet dependencies are reported by Assert(), but if a DatasourceReader fails for a
+// reason other than a missing key, Provide() returns the first such error.
 func Provide(inputs ...interface{}) error {
 	return globalGraph.Provide(inputs...)
 }
 
+// Provide() with a context. If the context is cancelled (or its deadline passes)
+// while datasources are being read, wiring stops and the context's error is
+// returned. Readers that implement ContextDatasourceReader receive the context.
+func ProvideContext(ctx context.Context, inputs ...interface{}) error {
+	return globalGraph.ProvideContext(ctx, inputs...)
+}
+
 // Given a function, call it with arguments assigned
 // from the graph. Additional arguments can be provided
 // for the sake of utility.
 //
 // Inject() will panic if the provided argument isn't a function,
-// or if the provided function accepts variadic arguments (because
-// that's not currently supported in the scope of inj).
+// or if a value can't be found for any of its fixed arguments.
+//
+// Variadic functions are supported. The variadic slice is taken from
+// an additional argument or graph node of the slice type if there
+// is one; otherwise it's populated with every additional argument
+// assignable to the element type or, if there aren't any of those,
+// every graph node assignable to the element type (in no particular
+// order). If nothing matches, the slice is empty.
+//
+// Arguments that are parameter objects (structs which embed inj.In)
+// are created for each call, and their tagged fields are assigned
+// from the additional arguments and the graph. See In for details.
 func Inject(fn interface{}, args ...interface{}) {
 	globalGraph.Inject(fn, args...)
 }
@@ -66,7 +103,8 @@ func Assert() (valid bool, errors []string) {
 
 // Add any number of Datasources, DatasourceReaders or DatasourceWriters
 // to the graph. Returns an error if any of the supplied arguments aren't
-// one of the accepted types.
+// one of the accepted types, or if a datasource fails when the graph is
+// re-Provided (see Provide()).
 //
 // Once added, the datasources will be active immediately, and the graph
 // will automatically re-Provide itself, so that any depdendencies that
@@ -75,3 +113,81 @@ func Assert() (valid bool, errors []string) {
 func AddDatasource(ds ...interface{}) error {
 	return globalGraph.AddDatasource(ds...)
 }
+
+// Insert an object into the graph and nominate some of its methods to be
+// called with arguments from the graph whenever the graph is connected.
+// If no method names are given, every exported method whose name starts
+// with "Set" is used. Any failures are reported by Assert().
+//
+// This is intended for types that can't have inj struct tags, such as
+// those from third party packages.
+func InjectMethods(obj interface{}, methods ...string) error {
+	return globalGraph.InjectMethods(obj, methods...)
+}
+
+// Declare dependencies for a struct type from outside the struct, which is
+// useful for types that can't have inj struct tags. The object is only used
+// to identify the type. Each field is declared with inj.Field():
+//
+//  inj.Configure(&thirdparty.Client{},
+//      inj.Field("Transport"),
+//      inj.Field("Timeout", inj.From("http.timeout")),
+//  )
+func Configure(obj interface{}, fields ...FieldSpec) error {
+	return globalGraph.Configure(obj, fields...)
+}
+
+// Register a function that converts datasource values of one type into
+// another, with the signature func(From) (To, error) or func(From) To.
+// Registered converters take precedence over the built-in conversions.
+func AddConverter(fn interface{}) error {
+	return globalGraph.AddConverter(fn)
+}
+
+// Set the maximum time the graph waits for a single datasource read. Readers
+// that implement ContextDatasourceReader have their reads cancelled; others
+// are abandoned. A read that times out is reported as a datasource failure
+// (see Provide()). A timeout of zero means reads never time out.
+func SetReadTimeout(timeout time.Duration) {
+	globalGraph.SetReadTimeout(timeout)
+}
+
+// Allow the global graph to convert numbers in ways that lose a fraction or
+// wrap them around, as Go's own conversions do.
+func SetLossyConversion(allow bool) {
+	globalGraph.SetLossyConversion(allow)
+}
+
+// Describe the wiring of the global graph: its nodes, their dependencies
+// and what met each of them. Compare two manifests with DiffManifests().
+func GetManifest() *Manifest {
+	return globalGraph.Manifest()
+}
+
+// Install modules into the global graph, along with any modules they
+// require. Each module is only installed once. See Module for details.
+func Install(modules ...*Module) error {
+	return globalGraph.Install(modules...)
+}
+
+// Register a function that wraps values of a type in the global graph before
+// they're assigned to dependencies or passed to Inject(), with the signature
+// func(T, ...) T or func(T, ...) (T, error). Decorators for the same type are
+// applied in the order they're registered.
+func Decorate(fn interface{}) error {
+	return globalGraph.Decorate(fn)
+}
+
+// Register an Observer with the global graph, which is told about values
+// being provided, dependencies being assigned (or not) and calls to Inject().
+// See package slogobserver for one that logs them.
+func AddObserver(o Observer) {
+	globalGraph.AddObserver(o)
+}
+
+// Register a flag for every datasource path of every struct in the global
+// graph, including fields declared with Configure(). See RegisterObjectFlags()
+// to register flags for objects that aren't in a graph.
+func RegisterFlags(fs *flag.FlagSet) {
+	globalGraph.RegisterFlags(fs)
+}
@@ -0,0 +1,109 @@
+/*
+Command injdiff compares two inj wiring manifests, and reports the nodes that
+were added or removed (or are now contributed by a different module), the
+fields whose providers or datasource keys changed, and dependencies that are
+newly unmet.
+
+Manifests are written by a graph's Manifest() function:
+
+	f, _ := os.Create("wiring.json")
+	inj.GetManifest().WriteTo(f)
+
+and compared with:
+
+	injdiff before.json after.json
+
+injdiff exits with status 1 if the wiring has changed, and 2 if it can't read
+a manifest, so it can gate changes in continuous integration. With the -unmet
+flag, only newly unmet dependencies cause a non-zero exit status; with -json,
+the changes are printed as JSON.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yourheropaul/inj"
+)
+
+func main() {
+
+	fs := flag.NewFlagSet("injdiff", flag.ExitOnError)
+	unmet := fs.Bool("unmet", false, "only fail if dependencies are newly unmet")
+	asJSON := fs.Bool("json", false, "print the changes as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: injdiff [flags] before.json after.json\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	os.Exit(run(fs.Arg(0), fs.Arg(1), *unmet, *asJSON, os.Stdout))
+}
+
+// Compare two manifest files, print the changes, and return an exit status
+func run(before, after string, unmet, asJSON bool, w io.Writer) int {
+
+	b, err := readManifest(before)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	a, err := readManifest(after)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	changes := inj.DiffManifests(b, a)
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		enc.Encode(changes)
+	} else {
+		for _, c := range changes {
+			fmt.Fprintln(w, c)
+		}
+	}
+
+	for _, c := range changes {
+		if !unmet || c.Kind == inj.DependencyUnmet {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func readManifest(filename string) (*inj.Manifest, error) {
+
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	m, err := inj.ReadManifest(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read manifest %s: %s", filename, err)
+	}
+
+	return m, nil
+}
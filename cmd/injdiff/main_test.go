@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourheropaul/inj"
+)
+
+func writeManifest(t *testing.T, dir, name string, m *inj.Manifest) string {
+
+	filename := filepath.Join(dir, name)
+	f, err := os.Create(filename)
+
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+
+	defer f.Close()
+
+	if _, err := m.WriteTo(f); err != nil {
+		t.Fatalf("WriteTo: %s", err)
+	}
+
+	return filename
+}
+
+// Changes should be printed, and reflected in the exit status
+func Test_Run(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "injdiff")
+
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	node := func(field inj.ManifestField) *inj.Manifest {
+		return &inj.Manifest{Nodes: []inj.ManifestNode{{Type: "*app.Server", Fields: []inj.ManifestField{field}}}}
+	}
+
+	before := writeManifest(t, dir, "before.json", node(inj.ManifestField{Path: ".Store", Provider: "*app.MemStore"}))
+	changed := writeManifest(t, dir, "changed.json", node(inj.ManifestField{Path: ".Store", Provider: "*app.RedisStore"}))
+	unmet := writeManifest(t, dir, "unmet.json", node(inj.ManifestField{Path: ".Store", Unmet: true}))
+	added := writeManifest(t, dir, "added.json", &inj.Manifest{Nodes: []inj.ManifestNode{
+		{Type: "*app.Server", Fields: []inj.ManifestField{{Path: ".Store", Provider: "*app.MemStore"}}},
+		{Type: "*app.Worker", Fields: []inj.ManifestField{{Path: ".Queue", Unmet: true}}},
+	}})
+
+	for i, test := range []struct {
+		after  string
+		unmet  bool
+		status int
+		output string
+	}{
+		{before, false, 0, ""},
+		{changed, false, 1, "*app.Server.Store: provider changed from *app.MemStore to *app.RedisStore\n"},
+		{changed, true, 0, "*app.Server.Store: provider changed from *app.MemStore to *app.RedisStore\n"},
+		{unmet, true, 1, "*app.Server.Store: dependency unmet (was *app.MemStore)\n"},
+		{added, true, 1, "*app.Worker: node added\n*app.Worker.Queue: dependency unmet (was new)\n"},
+		{filepath.Join(dir, "missing.json"), false, 2, ""},
+	} {
+		var b bytes.Buffer
+
+		if status := run(before, test.after, test.unmet, false, &b); status != test.status || b.String() != test.output {
+			t.Errorf("[%d] Expected status %d and %q, got %d and %q", i, test.status, test.output, status, b.String())
+		}
+	}
+
+	var b bytes.Buffer
+	run(before, changed, false, true, &b)
+
+	if !strings.Contains(b.String(), `"kind": "provider changed"`) {
+		t.Errorf("Unexpected JSON output %s", b.String())
+	}
+}
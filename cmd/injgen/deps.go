@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+)
+
+// Struct tag flags understood by inj
+const (
+	tagFlagUnexported = "unexported"
+	tagFlagPrefix     = "prefix"
+	tagFlagSecret     = "secret"
+	prefixKeyTag      = "injkey"
+)
+
+// A field that the graph would assign, found by walking a struct type in
+// the same way as the graph's findDependencies()
+type dependency struct {
+	paths          []string
+	fields         []string
+	typ            types.Type
+	prefix         bool
+	rules          []string
+	secret         bool
+	datasourceOnly bool
+	unknown        []string
+}
+
+// The dependency's path in its struct, in the graph's format (.A.B)
+func (d dependency) path() string {
+	return "." + strings.Join(d.fields, ".")
+}
+
+// Parse the value of an inj struct tag. As in the graph, a regexp rule takes
+// up the rest of the tag.
+func parseTag(tag string) (d dependency, unexported bool) {
+
+	parts := strings.Split(reflect.StructTag(tag).Get("inj"), ",")
+
+	// Tags that start with a comma have no datasource paths, as in the graph
+	pathless := len(parts) > 1 && parts[0] == ""
+
+	for i := 0; i < len(parts); i++ {
+
+		part := parts[i]
+
+		if strings.HasPrefix(part, "regexp=") {
+			part = strings.Join(parts[i:], ",")
+			i = len(parts)
+		}
+
+		switch part {
+		case "":
+			continue
+		case tagFlagSecret:
+			d.secret = true
+		case tagFlagUnexported:
+			unexported = true
+		case tagFlagPrefix:
+			d.prefix = true
+		default:
+			if isRule(part) {
+				d.rules = append(d.rules, part)
+			} else if pathless {
+				d.unknown = append(d.unknown, part)
+			} else {
+				d.paths = append(d.paths, part)
+			}
+		}
+	}
+
+	return
+}
+
+// Returns true if part of an inj tag is a validation rule. The graph treats
+// any part with an equals sign as a rule (and reports unknown ones).
+func isRule(part string) bool {
+	return part == "nonzero" || strings.Contains(part, "=")
+}
+
+// Finds dependencies within a package, which is where the generated code
+// will live (and so which unexported fields it can set)
+type finder struct {
+	pkg  *types.Package
+	deps []dependency
+	errs []error
+}
+
+func (f *finder) findDependencies(s *types.Struct, fields []string) {
+
+	for i := 0; i < s.NumFields(); i++ {
+		f.findFieldDependencies(s.Field(i), s.Tag(i), fields)
+	}
+}
+
+// Find the dependencies for a single struct field
+func (f *finder) findFieldDependencies(field *types.Var, tag string, fields []string) {
+
+	dep, unexported := parseTag(tag)
+
+	// Unexported fields are ignored unless they've opted in
+	if !field.Exported() && !unexported {
+		return
+	}
+
+	branch := append(append([]string{}, fields...), field.Name())
+
+	// Fields without tags may be structs with their own dependencies
+	if !strings.Contains(tag, "inj:") {
+
+		if s, ok := field.Type().Underlying().(*types.Struct); ok {
+			f.findDependencies(s, branch)
+		}
+
+		return
+	}
+
+	if !field.Exported() && field.Pkg() != f.pkg {
+		f.errs = append(f.errs, fmt.Errorf("Can't set unexported field %s of a struct from package %s", field.Name(), field.Pkg().Path()))
+		return
+	}
+
+	if len(dep.unknown) > 0 {
+		f.errs = append(f.errs, fmt.Errorf("Field %s has an unknown flag %s in its inj tag", field.Name(), dep.unknown[0]))
+		return
+	}
+
+	if len(dep.rules) > 0 {
+		f.errs = append(f.errs, fmt.Errorf("Field %s has validation rules, which generated wiring doesn't support", field.Name()))
+		return
+	}
+
+	// Prefixed structs are populated field by field
+	if s, ok := field.Type().Underlying().(*types.Struct); ok && dep.prefix {
+
+		prefixes := dep.paths
+
+		if len(prefixes) == 0 {
+			prefixes = []string{""}
+		}
+
+		found := len(f.deps)
+		f.findPrefixedDependencies(s, prefixes, branch)
+
+		// Every field of a secret struct is secret
+		for i := found; i < len(f.deps); i++ {
+			f.deps[i].secret = f.deps[i].secret || dep.secret
+		}
+
+		return
+	}
+
+	dep.fields = branch
+	dep.typ = field.Type()
+
+	f.deps = append(f.deps, dep)
+}
+
+// Find the dependencies for every field of a struct tagged with the prefix
+// flag, as the graph's findPrefixedDependencies() does
+func (f *finder) findPrefixedDependencies(s *types.Struct, prefixes []string, fields []string) {
+
+	for i := 0; i < s.NumFields(); i++ {
+
+		field, tag := s.Field(i), s.Tag(i)
+
+		if !field.Exported() {
+			continue
+		}
+
+		own, _ := parseTag(tag)
+		keyed := len(own.paths) == 0 && !own.prefix && (len(own.rules) > 0 || own.secret)
+
+		if strings.Contains(tag, "inj:") && !keyed {
+			f.findFieldDependencies(field, tag, fields)
+			continue
+		}
+
+		if len(own.rules) > 0 {
+			f.errs = append(f.errs, fmt.Errorf("Field %s has validation rules, which generated wiring doesn't support", field.Name()))
+			continue
+		}
+
+		key := strings.ToLower(field.Name())
+
+		if k, exists := reflect.StructTag(tag).Lookup(prefixKeyTag); exists {
+			key = k
+		}
+
+		if key == "-" {
+			continue
+		}
+
+		paths := make([]string, len(prefixes))
+
+		for j, prefix := range prefixes {
+			if prefix == "" {
+				paths[j] = key
+			} else {
+				paths[j] = prefix + "." + key
+			}
+		}
+
+		branch := append(append([]string{}, fields...), field.Name())
+
+		if st, ok := field.Type().Underlying().(*types.Struct); ok && !isLeafStruct(field.Type()) {
+			f.findPrefixedDependencies(st, paths, branch)
+			continue
+		}
+
+		f.deps = append(f.deps, dependency{
+			paths:          paths,
+			fields:         branch,
+			typ:            field.Type(),
+			secret:         own.secret,
+			datasourceOnly: true,
+		})
+	}
+}
+
+// Returns true if a struct type is parsed from a string, rather than
+// populated field by field
+func isLeafStruct(t types.Type) bool {
+
+	switch typeName(t) {
+	case "time.Time", "net/url.URL":
+		return true
+	}
+
+	return hasMethod(types.NewPointer(t), "UnmarshalText")
+}
+
+// The qualified name of a named type, or an empty string
+func typeName(t types.Type) string {
+
+	n, ok := t.(*types.Named)
+
+	if !ok || n.Obj().Pkg() == nil {
+		return ""
+	}
+
+	return n.Obj().Pkg().Path() + "." + n.Obj().Name()
+}
+
+// Returns true if a type has an exported method
+func hasMethod(t types.Type, name string) bool {
+	return types.NewMethodSet(t).Lookup(nil, name) != nil
+}
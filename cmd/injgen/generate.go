@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The import path of the inj package
+const injPath = "github.com/yourheropaul/inj"
+
+// A value returned by the providers function
+type node struct {
+	index     int
+	name      string
+	typ       types.Type
+	deps      []dependency
+	validator bool
+
+	// A later provider has the same type, so the graph won't contain this one
+	replaced bool
+}
+
+// Generates the wiring for a package
+type generator struct {
+	pkg      *types.Package
+	spec     spec
+	nodes    []*node
+	imports  map[string]string
+	helpers  map[string]bool
+	warnings []string
+	buf      bytes.Buffer
+}
+
+// What to generate
+type spec struct {
+	providers   string
+	datasources string
+	function    string
+}
+
+// Generate the wiring for the types returned by the providers function,
+// returning the formatted source and any warnings
+func generate(pkg *types.Package, s spec, provided []types.Type) ([]byte, []string, error) {
+
+	g := &generator{
+		pkg:     pkg,
+		spec:    s,
+		imports: map[string]string{injPath: "inj"},
+		helpers: make(map[string]bool),
+	}
+
+	if err := g.findNodes(provided); err != nil {
+		return nil, nil, err
+	}
+
+	g.wire()
+
+	return g.source(g.buf.Bytes()), g.warnings, nil
+}
+
+func (g *generator) warn(format string, args ...interface{}) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+///////////////////////////////////////////////
+// Nodes
+///////////////////////////////////////////////
+
+// Work out what each provider depends on, in the way the graph would
+func (g *generator) findNodes(provided []types.Type) error {
+
+	errs := make([]string, 0)
+
+	for i, t := range provided {
+
+		n := &node{index: i, name: fmt.Sprintf("n%d", i), typ: t}
+		g.nodes = append(g.nodes, n)
+
+		// The graph keeps the last value of each type
+		for _, m := range g.nodes[:i] {
+			if types.Identical(m.typ, t) {
+				m.replaced = true
+				g.warn("Provider %d replaces provider %d, which has the same type (%s)", i, m.index, g.reflectString(t))
+			}
+		}
+
+		if sig, ok := method(t, "Validate"); ok && sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+			sig.Results().At(0).Type().String() == "error" {
+			n.validator = true
+		}
+
+		f := &finder{pkg: g.pkg}
+
+		switch u := t.Underlying().(type) {
+		case *types.Pointer:
+			if s, ok := u.Elem().Underlying().(*types.Struct); ok {
+				f.findDependencies(s, nil)
+			}
+		case *types.Struct:
+			f.findDependencies(u, nil)
+
+			if len(f.deps) > 0 {
+				errs = append(errs, fmt.Sprintf("Provider %d (%s) is a struct value, so its dependencies can never be set", i, g.reflectString(t)))
+				continue
+			}
+		case *types.Interface:
+			g.warn("Provider %d has the interface type %s; the graph uses its dynamic type, and wires its dependencies", i, g.reflectString(t))
+		}
+
+		for _, e := range f.errs {
+			errs = append(errs, fmt.Sprintf("Provider %d (%s): %s", i, g.reflectString(t), e))
+		}
+
+		n.deps = f.deps
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// Find the method of a type with a given name, if there is one
+func method(t types.Type, name string) (*types.Signature, bool) {
+
+	sel := types.NewMethodSet(t).Lookup(nil, name)
+
+	if sel == nil {
+		return nil, false
+	}
+
+	sig, ok := sel.Type().(*types.Signature)
+
+	return sig, ok
+}
+
+// Find the node that the graph would assign to a dependency of another node
+func (g *generator) candidate(parent *node, dep dependency) *node {
+
+	found := make([]*node, 0)
+
+	for _, n := range g.nodes {
+		if n != parent && !n.replaced && types.AssignableTo(n.typ, dep.typ) {
+			found = append(found, n)
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	// The graph would choose at random
+	if len(found) > 1 {
+		g.warn("%s%s could be assigned from any of %d providers; using provider %d", g.reflectString(parent.typ), dep.path(), len(found), found[0].index)
+	}
+
+	return found[0]
+}
+
+///////////////////////////////////////////////
+// Code
+///////////////////////////////////////////////
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// Write a type in the generated package, importing its package if necessary
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *generator) qualifier(p *types.Package) string {
+
+	if p == g.pkg {
+		return ""
+	}
+
+	if name, exists := g.imports[p.Path()]; exists {
+		return name
+	}
+
+	name := p.Name()
+
+	// Avoid clashes between packages with the same name
+	for i := 2; g.nameTaken(name); i++ {
+		name = p.Name() + strconv.Itoa(i)
+	}
+
+	g.imports[p.Path()] = name
+
+	return name
+}
+
+func (g *generator) nameTaken(name string) bool {
+
+	for _, n := range g.imports {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The type as the graph (and the reflect package) would describe it
+func (g *generator) reflectString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string { return p.Name() })
+}
+
+// Write the wiring function
+func (g *generator) wire() {
+
+	g.printf("// %s assigns the dependencies of the values returned by %s(), in the same\n", g.spec.function, g.spec.providers)
+	g.printf("// way as providing them to an inj graph with the given datasources, and returns\n")
+	g.printf("// the failures that the graph's Assert() would report.\n")
+	g.printf("func %s(nodes []interface{}, readers ...inj.DatasourceReader) []error {\n\n", g.spec.function)
+	g.printf("errs := make([]error, 0)\n\n")
+
+	for i, n := range g.nodes {
+		if !n.replaced && (len(n.deps) > 0 || n.validator || g.used(n)) {
+			g.printf("%s := nodes[%d].(%s)\n", n.name, i, g.typeString(n.typ))
+		}
+	}
+
+	for _, n := range g.nodes {
+
+		if n.replaced {
+			continue
+		}
+
+		for _, dep := range n.deps {
+			g.printf("\n")
+			g.assign(n, dep)
+		}
+	}
+
+	for _, n := range g.nodes {
+
+		if n.replaced || !n.validator {
+			continue
+		}
+
+		g.imports["fmt"] = "fmt"
+
+		g.printf("\nif err := %s.Validate(); err != nil {\n", n.name)
+		g.printf("errs = append(errs, fmt.Errorf(\"%s: %%w\", err))\n", g.reflectString(n.typ))
+		g.printf("}\n")
+	}
+
+	g.printf("\nreturn errs\n}\n")
+
+	g.writeHelpers()
+}
+
+// Returns true if a node is assigned to another
+func (g *generator) used(n *node) bool {
+
+	for _, m := range g.nodes {
+
+		if m.replaced {
+			continue
+		}
+
+		for _, dep := range m.deps {
+			if !dep.datasourceOnly && g.candidateQuiet(m, dep) == n {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Find a candidate without warnings
+func (g *generator) candidateQuiet(parent *node, dep dependency) *node {
+
+	warnings := g.warnings
+	n := g.candidate(parent, dep)
+	g.warnings = warnings
+
+	return n
+}
+
+// Write the code that assigns one dependency
+func (g *generator) assign(n *node, dep dependency) {
+
+	field := n.name + "." + strings.Join(dep.fields, ".")
+	name := g.reflectString(n.typ) + dep.path()
+
+	// What happens if no datasource has a value
+	fallback := func() {
+
+		if c := g.candidate(n, dep); c != nil {
+			g.printf("%s = %s\n", field, c.name)
+			return
+		}
+
+		g.imports["errors"] = "errors"
+		g.printf("errs = append(errs, errors.New(%q))\n", "Couldn't find suitable dependency for "+g.reflectString(dep.typ))
+	}
+
+	if len(dep.paths) == 0 {
+		g.printf("// %s\n", name)
+		fallback()
+		return
+	}
+
+	g.printf("// %s, from %s\n", name, strings.Join(dep.paths, " or "))
+
+	g.helpers["injRead"] = true
+	g.imports["fmt"] = "fmt"
+
+	quoted := make([]string, len(dep.paths))
+
+	for i, path := range dep.paths {
+		quoted[i] = strconv.Quote(path)
+	}
+
+	if dep.datasourceOnly {
+		g.printf("if _, err := injRead(readers, ")
+	} else {
+		g.printf("if found, err := injRead(readers, ")
+	}
+
+	g.convert(field, dep)
+
+	g.printf(", %s); err != nil {\n", strings.Join(quoted, ", "))
+	g.printf("errs = append(errs, fmt.Errorf(\"%s: %%w\", err))\n", name)
+
+	if !dep.datasourceOnly {
+		g.printf("} else if !found {\n")
+		fallback()
+	}
+
+	g.printf("}\n")
+}
+
+// Write a function that converts a datasource value and assigns it to a
+// field, as the graph's built-in conversions would
+func (g *generator) convert(field string, dep dependency) {
+
+	t := dep.typ
+	typ := g.typeString(t)
+	to := strconv.Quote(g.reflectString(t))
+
+	g.printf("func(v interface{}) error {\n")
+
+	helper, arg := g.converter(t)
+	exact := helper != "" && helpers[helper].result == typ
+	_, basic := t.(*types.Basic)
+
+	// Values of the right type are always used as they are (which the
+	// helpers do for basic types)
+	if helper == "" || !(exact || basic) {
+		g.printf("if x, ok := v.(%s); ok {\n%s = x\nreturn nil\n}\n", typ, field)
+	}
+
+	if helper == "" {
+		g.printf("return fmt.Errorf(\"Can't convert %%T to %%s\", v, %s)\n}", to)
+		return
+	}
+
+	g.helpers[helper] = true
+	value := typ + "(x)"
+
+	if exact {
+		value = "x"
+	}
+
+	g.printf("x, err := %s(v%s, %s)\n", helper, arg, to)
+	g.printf("if err != nil {\n")
+
+	// Conversion errors can quote the value, so secrets get a vaguer one
+	if isSecret(dep) {
+		g.imports["errors"] = "errors"
+		g.printf("return errors.New(%q)\n", "Can't convert a secret value to "+g.reflectString(t))
+	} else {
+		g.printf("return err\n")
+	}
+
+	g.printf("}\n%s = %s\nreturn nil\n}", field, value)
+}
+
+// Returns true if a dependency's value mustn't be reported, as the graph's
+// secret() does
+func isSecret(dep dependency) bool {
+
+	t := dep.typ
+
+	for {
+		p, ok := t.(*types.Pointer)
+
+		if !ok {
+			break
+		}
+
+		t = p.Elem()
+	}
+
+	return dep.secret || typeName(t) == injPath+".Secret"
+}
+
+// Find the helper that converts datasource values to a type, and any
+// extra argument it takes
+func (g *generator) converter(t types.Type) (string, string) {
+
+	if typeName(t) == "time.Duration" {
+		return "injDuration", ""
+	}
+
+	if s, ok := t.Underlying().(*types.Slice); ok {
+		if b, ok := s.Elem().(*types.Basic); ok && b.Kind() == types.String {
+			return "injStrings", ""
+		}
+	}
+
+	b, ok := t.Underlying().(*types.Basic)
+
+	if !ok {
+		return "", ""
+	}
+
+	switch b.Kind() {
+	case types.String:
+		return "injString", ""
+	case types.Bool:
+		return "injBool", ""
+	case types.Int, types.Int64:
+		return "injInt", ", 64"
+	case types.Int8:
+		return "injInt", ", 8"
+	case types.Int16:
+		return "injInt", ", 16"
+	case types.Int32:
+		return "injInt", ", 32"
+	case types.Uint, types.Uint64, types.Uintptr:
+		return "injUint", ", 64"
+	case types.Uint8:
+		return "injUint", ", 8"
+	case types.Uint16:
+		return "injUint", ", 16"
+	case types.Uint32:
+		return "injUint", ", 32"
+	case types.Float32:
+		return "injFloat", ", 32"
+	case types.Float64:
+		return "injFloat", ", 64"
+	}
+
+	return "", ""
+}
+
+// Write the helpers that the wiring function uses
+func (g *generator) writeHelpers() {
+
+	names := make([]string, 0, len(g.helpers))
+
+	for name := range g.helpers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		h := helpers[name]
+
+		for _, path := range h.imports {
+			g.imports[path] = path
+		}
+
+		g.printf("\n%s", h.source)
+	}
+}
+
+// Add the header and imports to the generated code, and format it
+func (g *generator) source(body []byte) []byte {
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "// Code generated by injgen. DO NOT EDIT.\n\npackage %s\n\n", g.pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	b.WriteString("import (\n")
+
+	// The standard library first, then everything else
+	for _, std := range []bool{true, false} {
+
+		for _, path := range paths {
+
+			if isStandard(path) != std {
+				continue
+			}
+
+			if name := g.imports[path]; name != path && name != path[strings.LastIndex(path, "/")+1:] {
+				fmt.Fprintf(&b, "%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&b, "%q\n", path)
+			}
+		}
+
+		b.WriteString("\n")
+	}
+
+	b.WriteString(")\n\n")
+	b.Write(body)
+
+	formatted, err := format.Source(b.Bytes())
+
+	// Unformatted code is more useful than nothing
+	if err != nil {
+		return b.Bytes()
+	}
+
+	return formatted
+}
+
+// Standard library import paths don't have a dot in their first element
+func isStandard(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
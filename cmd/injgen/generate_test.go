@@ -0,0 +1,277 @@
+package main
+
+import (
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// An importer that type-checks the inj package from the source in this
+// repository, and everything else from the standard library
+type testImporter struct {
+	fset  *token.FileSet
+	std   types.Importer
+	cache map[string]*types.Package
+}
+
+func newTestImporter(fset *token.FileSet) *testImporter {
+	return &testImporter{fset, importer.ForCompiler(fset, "source", nil), make(map[string]*types.Package)}
+}
+
+func (i *testImporter) Import(path string) (*types.Package, error) {
+
+	if path != injPath {
+		return i.std.Import(path)
+	}
+
+	if p, exists := i.cache[path]; exists {
+		return p, nil
+	}
+
+	pkg, err := build.ImportDir(filepath.Join("..", ".."), 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, len(pkg.GoFiles))
+
+	for j, name := range pkg.GoFiles {
+		files[j] = filepath.Join(pkg.Dir, name)
+	}
+
+	l, err := loadFiles(i.fset, path, files, i.std)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i.cache[path] = l.pkg
+
+	return l.pkg, nil
+}
+
+// Generate the wiring for some source, which is written to a temporary file
+func generateSource(t *testing.T, src string) ([]byte, []string, error) {
+
+	dir, err := ioutil.TempDir("", "injgen")
+
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.go")
+
+	if err := ioutil.WriteFile(filename, []byte(src), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	fset := token.NewFileSet()
+	l, err := loadFiles(fset, "app", []string{filename}, newTestImporter(fset))
+
+	if err != nil {
+		t.Fatalf("loadFiles: %s", err)
+	}
+
+	fn, err := l.findProviders("")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provided, err := l.providedTypes(fn)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return generate(l.pkg, spec{providers: fn.Name.Name, function: "wire"}, provided)
+}
+
+// The wiring for the test package should assign every dependency
+func Test_Generate(t *testing.T) {
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "app", "app.go"))
+
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+
+	src, warnings, err := generateSource(t, string(b))
+
+	if err != nil {
+		t.Fatalf("generate: %s", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("Generated code doesn't parse: %s\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		"func wire(nodes []interface{}, readers ...inj.DatasourceReader) []error {",
+		"n2 := nodes[2].(Logger)",
+		"n0.Config = n1",
+		"n0.cache = n3",
+		"n0.Nested.Log = n2",
+		`}, "cache.size", "size"); err != nil {`,
+		`}, "db.port_number"); err != nil {`,
+		"n1.DB.Timeout = x",
+		"n1.Level = Level(x)",
+		`errs = append(errs, errors.New("Couldn't find suitable dependency for string"))`,
+		`errs = append(errs, fmt.Errorf("*app.Server: %w", err))`,
+		"func injDuration(",
+		`x, err := injInt(v, 8, "int8")`,
+		`return errors.New("Can't convert a secret value to int")`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Generated code doesn't contain %q:\n%s", want, src)
+		}
+	}
+
+	for _, unwanted := range []string{"db.ignored", "injUint8", "n1.Name = string(x)"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("Generated code contains %q", unwanted)
+		}
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "interface type app.Logger") {
+		t.Errorf("Unexpected warnings %q", warnings)
+	}
+}
+
+// Ambiguous and replaced providers should be reported, but wired as the
+// graph would wire them
+func Test_GenerateWarnings(t *testing.T) {
+
+	src, warnings, err := generateSource(t, `package app
+
+type A struct{}
+type B struct{}
+type Needs struct {
+	V interface{} `+"`inj:\"\"`"+`
+}
+
+//inj:providers
+func providers() []interface{} {
+	return []interface{}{&Needs{}, &A{}, &A{}, &B{}}
+}
+`)
+
+	if err != nil {
+		t.Fatalf("generate: %s", err)
+	}
+
+	if !strings.Contains(string(src), "n0.V = n2") || strings.Contains(string(src), "n1 :=") {
+		t.Errorf("Unexpected wiring:\n%s", src)
+	}
+
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "Provider 2 replaces provider 1") ||
+		!strings.Contains(warnings[1], "any of 2 providers; using provider 2") {
+		t.Errorf("Unexpected warnings %q", warnings)
+	}
+}
+
+// Packages that can't be wired without reflection should be rejected
+func Test_GenerateErrors(t *testing.T) {
+
+	for i, test := range []struct {
+		src, err string
+	}{
+		{`func other() []interface{} { return nil }`, "Can't find a function marked with //inj:providers"},
+		{"//inj:providers\nfunc providers(n int) []interface{} { return nil }", "must be declared as func() []interface{}"},
+		{"//inj:providers\nfunc providers() []interface{} { p := []interface{}{}; return p }", "must return a slice literal"},
+		{"//inj:providers\nfunc providers() []interface{} { return []interface{}{nil} }", "providers can't be nil"},
+		{"//inj:providers\nfunc providers() []interface{} { return []interface{}{Config{}} }", "Provider 0 (app.Config) is a struct value"},
+		{"//inj:providers\nfunc providers() []interface{} { return []interface{}{&Checked{}} }", "Field Port has validation rules"},
+		{"//inj:providers\nfunc providers() []interface{} { return []interface{}{&Flagged{}} }", "Field Port has an unknown flag x"},
+	} {
+		src := "package app\n\ntype Config struct {\n\tPort int `inj:\"port\"`\n}\n\n" +
+			"type Checked struct {\n\tPort int `inj:\"port,min=1\"`\n}\n\n" +
+			"type Flagged struct {\n\tPort int `inj:\",x\"`\n}\n\n" + test.src + "\n"
+
+		if _, _, err := generateSource(t, src); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("[%d] Expected an error containing %q, got %v", i, test.err, err)
+		}
+	}
+}
+
+// The generated test should pass for the test package, which means that
+// its wiring matches the graph's
+func Test_GeneratedWiringMatchesGraph(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("Skipping go test of generated code in short mode")
+	}
+
+	gobin, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("The go command isn't available")
+	}
+
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+
+	if err != nil {
+		t.Fatalf("Abs: %s", err)
+	}
+
+	gopath, err := ioutil.TempDir("", "injgen")
+
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+
+	defer os.RemoveAll(gopath)
+
+	// A GOPATH containing this repository and a copy of the test package
+	link := filepath.Join(gopath, "src", "github.com", "yourheropaul", "inj")
+	dir := filepath.Join(gopath, "src", "app")
+
+	if err := os.MkdirAll(filepath.Dir(link), 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+
+	if err := os.Symlink(root, link); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "app", "app.go"))
+
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.go"), b, 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	defer func(p string) { build.Default.GOPATH = p }(build.Default.GOPATH)
+	build.Default.GOPATH = gopath
+	t.Setenv("GO111MODULE", "off")
+
+	if err := run(dir, "inj_wiring.go", true, spec{datasources: "datasources"}); err != nil {
+		t.Fatalf("run: %s", err)
+	}
+
+	cmd := exec.Command(gobin, "test", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPATH="+gopath, "GO111MODULE=off")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go test failed: %s\n%s", err, out)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+// A function that's copied into generated code when it's needed
+type helper struct {
+	result  string
+	imports []string
+	source  string
+}
+
+// The helpers that generated code uses to read and convert datasource
+// values, following the graph's built-in conversions
+var helpers = map[string]helper{
+	"injRead": {"", []string{"errors", "fmt"}, `// Read the first datasource path that a reader has a value for, which set()
+// converts and assigns. Missing keys fall through to the next reader, but any
+// other failure (including a value that can't be converted) is returned.
+func injRead(readers []inj.DatasourceReader, set func(interface{}) error, paths ...string) (bool, error) {
+
+	for _, path := range paths {
+		for _, r := range readers {
+
+			v, err := r.Read(path)
+
+			if err != nil && !errors.Is(err, inj.ErrNotFound) {
+				return false, &inj.DatasourceError{Path: path, Reader: r, Err: err}
+			}
+
+			if err != nil {
+				continue
+			}
+
+			if err := set(v); err != nil {
+				return true, fmt.Errorf("Couldn't convert %s from datasource %T: %w%s", path, r, err, injProvenance(r, path))
+			}
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Describe where a value came from, if the reader records it
+func injProvenance(r inj.DatasourceReader, path string) string {
+
+	if pr, ok := r.(interface {
+		Provenance(string) (inj.Provenance, bool)
+	}); ok {
+		if p, exists := pr.Provenance(path); exists {
+			return " (" + p.String() + ")"
+		}
+	}
+
+	return ""
+}
+`},
+	"injString": {"string", []string{"fmt", "reflect"}, `// Convert a datasource value to a string
+func injString(v interface{}, to string) (string, error) {
+
+	r := reflect.ValueOf(v)
+
+	switch r.Kind() {
+	case reflect.String:
+		return r.String(), nil
+	case reflect.Slice:
+		if k := r.Type().Elem().Kind(); k == reflect.Uint8 || k == reflect.Int32 {
+			return r.Convert(reflect.TypeOf("")).String(), nil
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v), nil
+	case reflect.Invalid:
+		return "", fmt.Errorf("Can't convert a nil value to %s", to)
+	}
+
+	return "", fmt.Errorf("Can't convert %T to %s", v, to)
+}
+`},
+	"injBool": {"bool", []string{"fmt", "reflect", "strconv"}, `// Convert a datasource value to a bool
+func injBool(v interface{}, to string) (bool, error) {
+
+	r := reflect.ValueOf(v)
+
+	switch r.Kind() {
+	case reflect.Bool:
+		return r.Bool(), nil
+	case reflect.String:
+		return strconv.ParseBool(r.String())
+	case reflect.Invalid:
+		return false, fmt.Errorf("Can't convert a nil value to %s", to)
+	}
+
+	return false, fmt.Errorf("Can't convert %T to %s", v, to)
+}
+`},
+	"injInt": {"int64", []string{"fmt", "math", "reflect", "strconv"}, `// Convert a datasource value to a signed integer of a given size, without
+// losing a fraction or overflowing
+func injInt(v interface{}, bits int, to string) (int64, error) {
+
+	r := reflect.ValueOf(v)
+	min, max := int64(-1)<<(bits-1), int64(1)<<(bits-1)-1
+
+	switch r.Kind() {
+	case reflect.String:
+		return strconv.ParseInt(r.String(), 0, bits)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := r.Int(); i >= min && i <= max {
+			return i, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := r.Uint(); u <= uint64(max) {
+			return int64(u), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		f := r.Float()
+
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("Can't convert %v to %s without losing its fraction", v, to)
+		}
+
+		if f >= float64(min) && f < -float64(min) {
+			return int64(f), nil
+		}
+	case reflect.Invalid:
+		return 0, fmt.Errorf("Can't convert a nil value to %s", to)
+	default:
+		return 0, fmt.Errorf("Can't convert %T to %s", v, to)
+	}
+
+	return 0, fmt.Errorf("%v overflows %s", v, to)
+}
+`},
+	"injUint": {"uint64", []string{"fmt", "math", "reflect", "strconv"}, `// Convert a datasource value to an unsigned integer of a given size,
+// without losing a fraction or a sign, or overflowing
+func injUint(v interface{}, bits int, to string) (uint64, error) {
+
+	r := reflect.ValueOf(v)
+	max := uint64(1)<<bits - 1
+
+	switch r.Kind() {
+	case reflect.String:
+		return strconv.ParseUint(r.String(), 0, bits)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := r.Int()
+
+		if i < 0 {
+			return 0, fmt.Errorf("Can't convert negative %v to %s", v, to)
+		}
+
+		if uint64(i) <= max {
+			return uint64(i), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := r.Uint(); u <= max {
+			return u, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		f := r.Float()
+
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("Can't convert %v to %s without losing its fraction", v, to)
+		}
+
+		if f < 0 {
+			return 0, fmt.Errorf("Can't convert negative %v to %s", v, to)
+		}
+
+		if f < float64(max)+1 {
+			return uint64(f), nil
+		}
+	case reflect.Invalid:
+		return 0, fmt.Errorf("Can't convert a nil value to %s", to)
+	default:
+		return 0, fmt.Errorf("Can't convert %T to %s", v, to)
+	}
+
+	return 0, fmt.Errorf("%v overflows %s", v, to)
+}
+`},
+	"injFloat": {"float64", []string{"fmt", "math", "reflect", "strconv"}, `// Convert a datasource value to a float of a given size, without
+// overflowing
+func injFloat(v interface{}, bits int, to string) (float64, error) {
+
+	r := reflect.ValueOf(v)
+
+	switch r.Kind() {
+	case reflect.String:
+		return strconv.ParseFloat(r.String(), bits)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(r.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := r.Float()
+
+		if bits == 32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+			return 0, fmt.Errorf("%v overflows %s", v, to)
+		}
+
+		return f, nil
+	case reflect.Invalid:
+		return 0, fmt.Errorf("Can't convert a nil value to %s", to)
+	}
+
+	return 0, fmt.Errorf("Can't convert %T to %s", v, to)
+}
+`},
+	"injDuration": {"time.Duration", []string{"fmt", "reflect", "time"}, `// Convert a datasource value to a duration, which is parsed from strings;
+// numbers have no unit, so they aren't converted
+func injDuration(v interface{}, to string) (time.Duration, error) {
+
+	if d, ok := v.(time.Duration); ok {
+		return d, nil
+	}
+
+	r := reflect.ValueOf(v)
+
+	switch r.Kind() {
+	case reflect.String:
+		return time.ParseDuration(r.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 0, fmt.Errorf("Can't convert %v to %s without a unit", v, to)
+	case reflect.Invalid:
+		return 0, fmt.Errorf("Can't convert a nil value to %s", to)
+	}
+
+	return 0, fmt.Errorf("Can't convert %T to %s", v, to)
+}
+`},
+	"injStrings": {"[]string", []string{"fmt", "reflect", "strings"}, `// Convert a datasource value to a slice of strings; strings are split on
+// commas, and the elements of other slices are converted one by one
+func injStrings(v interface{}, to string) ([]string, error) {
+
+	r := reflect.ValueOf(v)
+
+	switch r.Kind() {
+	case reflect.String:
+		if r.Len() == 0 {
+			return []string{}, nil
+		}
+
+		parts := strings.Split(r.String(), ",")
+
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		return parts, nil
+	case reflect.Slice, reflect.Array:
+		s := make([]string, r.Len())
+
+		for i := range s {
+
+			e := r.Index(i)
+
+			for e.Kind() == reflect.Interface && !e.IsNil() {
+				e = e.Elem()
+			}
+
+			switch e.Kind() {
+			case reflect.String:
+				s[i] = e.String()
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64:
+				s[i] = fmt.Sprint(e.Interface())
+			case reflect.Interface:
+				return nil, fmt.Errorf("Element %d: Can't convert a nil value to string", i)
+			default:
+				return nil, fmt.Errorf("Element %d: Can't convert %s to string", i, e.Type())
+			}
+		}
+
+		return s, nil
+	case reflect.Invalid:
+		return nil, fmt.Errorf("Can't convert a nil value to %s", to)
+	}
+
+	return nil, fmt.Errorf("Can't convert %T to %s", v, to)
+}
+`},
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// The comment that marks the providers function
+const providersDirective = "//inj:providers"
+
+// A parsed and type-checked package
+type loaded struct {
+	fset  *token.FileSet
+	pkg   *types.Package
+	files []*ast.File
+	info  *types.Info
+}
+
+// Parse and type-check the package in a directory, skipping a file (which
+// is usually previously generated code, and may be out of date)
+func loadDir(dir, skip string) (*loaded, error) {
+
+	bp, err := build.ImportDir(dir, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filenames := make([]string, 0, len(bp.GoFiles))
+
+	for _, name := range bp.GoFiles {
+		if name != skip {
+			filenames = append(filenames, filepath.Join(dir, name))
+		}
+	}
+
+	fset := token.NewFileSet()
+
+	return loadFiles(fset, bp.ImportPath, filenames, importer.ForCompiler(fset, "source", nil))
+}
+
+// Parse and type-check some files
+func loadFiles(fset *token.FileSet, path string, filenames []string, imp types.Importer) (*loaded, error) {
+
+	l := &loaded{
+		fset: fset,
+		info: &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+		},
+	}
+
+	for _, name := range filenames {
+
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+
+		if err != nil {
+			return nil, err
+		}
+
+		l.files = append(l.files, f)
+	}
+
+	config := &types.Config{Importer: imp}
+
+	pkg, err := config.Check(path, fset, l.files, l.info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l.pkg = pkg
+
+	return l, nil
+}
+
+// Find the providers function, either by name or by its directive
+func (l *loaded) findProviders(name string) (*ast.FuncDecl, error) {
+
+	for _, f := range l.files {
+		for _, decl := range f.Decls {
+
+			fn, ok := decl.(*ast.FuncDecl)
+
+			if !ok || fn.Recv != nil {
+				continue
+			}
+
+			if name != "" && fn.Name.Name == name {
+				return fn, nil
+			}
+
+			if name == "" && fn.Doc != nil {
+				for _, c := range fn.Doc.List {
+					if strings.TrimSpace(c.Text) == providersDirective {
+						return fn, nil
+					}
+				}
+			}
+		}
+	}
+
+	if name != "" {
+		return nil, fmt.Errorf("Can't find the providers function %s", name)
+	}
+
+	return nil, fmt.Errorf("Can't find a function marked with %s", providersDirective)
+}
+
+// Find the types of the values returned by the providers function, which must
+// be declared as func() []interface{} and return a slice literal
+func (l *loaded) providedTypes(fn *ast.FuncDecl) ([]types.Type, error) {
+
+	sig := l.info.Defs[fn.Name].Type().(*types.Signature)
+
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 || sig.Results().At(0).Type().String() != "[]interface{}" {
+		return nil, fmt.Errorf("%s: %s must be declared as func() []interface{}", l.fset.Position(fn.Pos()), fn.Name.Name)
+	}
+
+	var ret *ast.ReturnStmt
+
+	if n := len(fn.Body.List); n > 0 {
+		ret, _ = fn.Body.List[n-1].(*ast.ReturnStmt)
+	}
+
+	if ret == nil || len(ret.Results) != 1 {
+		return nil, fmt.Errorf("%s: %s must end by returning a slice literal", l.fset.Position(fn.Pos()), fn.Name.Name)
+	}
+
+	lit, ok := ret.Results[0].(*ast.CompositeLit)
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %s must return a slice literal", l.fset.Position(ret.Pos()), fn.Name.Name)
+	}
+
+	provided := make([]types.Type, 0, len(lit.Elts))
+
+	for _, e := range lit.Elts {
+
+		if _, ok := e.(*ast.KeyValueExpr); ok {
+			return nil, fmt.Errorf("%s: providers can't be indexed", l.fset.Position(e.Pos()))
+		}
+
+		t := l.info.Types[e].Type
+
+		if b, ok := t.(*types.Basic); ok && b.Kind() == types.UntypedNil {
+			return nil, fmt.Errorf("%s: providers can't be nil", l.fset.Position(e.Pos()))
+		}
+
+		provided = append(provided, t)
+	}
+
+	return provided, nil
+}
@@ -0,0 +1,127 @@
+/*
+Command injgen generates plain Go code that wires a package's inj graph
+without reflection.
+
+The values to wire are declared by a providers function, which must take no
+arguments and return a slice literal:
+
+	//inj:providers
+	func providers() []interface{} {
+		return []interface{}{&Server{}, &Config{}, NewCache()}
+	}
+
+Running injgen in the package's directory (usually with go generate) writes
+inj_wiring.go, which contains a function that assigns the same dependencies
+as providing the values to an inj graph, including reading and converting
+datasource values:
+
+	nodes := providers()
+	errs := wireProviders(nodes, inj.EnvDatasource("APP"))
+
+With the -test flag, injgen also writes inj_wiring_test.go, which checks that
+the generated wiring matches the graph's. The providers function must then
+return new values each time it's called, and the -datasources flag can name a
+func() []inj.DatasourceReader that supplies the test's datasources.
+
+The generated code follows the graph's tags (datasource paths, and the
+unexported and prefix flags), its Validator hook and its built-in conversions
+of strings and numbers to basic types, durations and string slices. Values of
+other types are only used if a datasource returns exactly the right type.
+Validation rules, converters, writers and injection methods aren't supported.
+Where the graph would choose between several providers at random, injgen
+chooses the first, and prints a warning.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+
+	var s spec
+
+	fs := flag.NewFlagSet("injgen", flag.ExitOnError)
+	fs.StringVar(&s.providers, "providers", "", "the providers function (by default, the function marked with "+providersDirective+")")
+	fs.StringVar(&s.function, "func", "", "the name of the generated function (by default, wire followed by the providers function's name)")
+	fs.StringVar(&s.datasources, "datasources", "", "a function that returns the datasources for the generated test")
+	output := fs.String("o", "inj_wiring.go", "the file to write, in the package directory")
+	test := fs.Bool("test", false, "also write a test that checks the wiring against an inj graph")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: injgen [flags] [directory]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(os.Args[1:])
+
+	dir := "."
+
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	if err := run(dir, *output, *test, s); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Generate the wiring (and optionally the test) for the package in a directory
+func run(dir, output string, test bool, s spec) error {
+
+	l, err := loadDir(dir, output)
+
+	if err != nil {
+		return err
+	}
+
+	fn, err := l.findProviders(s.providers)
+
+	if err != nil {
+		return err
+	}
+
+	s.providers = fn.Name.Name
+
+	if s.function == "" {
+		s.function = "wire" + strings.ToUpper(s.providers[:1]) + s.providers[1:]
+	}
+
+	provided, err := l.providedTypes(fn)
+
+	if err != nil {
+		return err
+	}
+
+	src, warnings, err := generate(l.pkg, s, provided)
+
+	if err != nil {
+		return err
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "injgen: %s\n", w)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, output), src, 0666); err != nil {
+		return err
+	}
+
+	if !test {
+		return nil
+	}
+
+	src, err = generateTest(l.pkg.Name(), s)
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, strings.TrimSuffix(output, ".go")+"_test.go"), src, 0666)
+}
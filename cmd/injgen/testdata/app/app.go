@@ -0,0 +1,111 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yourheropaul/inj"
+)
+
+type Logger interface {
+	Log(string)
+}
+
+type stdLogger struct {
+	lines []string
+}
+
+func (l *stdLogger) Log(s string) {
+	l.lines = append(l.lines, s)
+}
+
+type Level string
+
+type DBConfig struct {
+	Host    string
+	Port    int `injkey:"port_number"`
+	Timeout time.Duration
+	Tags    []string
+	Ignored string `injkey:"-"`
+}
+
+type Config struct {
+	Name    string   `inj:"app.name"`
+	Debug   bool     `inj:"app.debug"`
+	Workers int8     `inj:"app.workers"`
+	Ratio   float32  `inj:"app.ratio"`
+	Limit   uint     `inj:"app.limit"`
+	Level   Level    `inj:"app.level"`
+	DB      DBConfig `inj:"db,prefix"`
+	Missing string   `inj:"app.missing"`
+	Broken  string   `inj:"app.broken"`
+
+	// Values that can't be converted
+	Retries int8          `inj:"app.retries"`
+	Grace   time.Duration `inj:"app.grace"`
+	Token   int           `inj:"app.token,secret"`
+}
+
+type Cache struct {
+	Size int `inj:"cache.size,size"`
+}
+
+type Server struct {
+	Config *Config `inj:""`
+	Log    Logger  `inj:""`
+	Port   int     `inj:"server.port"`
+	cache  *Cache  `inj:",unexported"`
+	Nested struct {
+		Log Logger `inj:""`
+	}
+}
+
+func (s *Server) Validate() error {
+
+	if s.Port == 0 {
+		return errors.New("No port")
+	}
+
+	return nil
+}
+
+//inj:providers
+func providers() []interface{} {
+	return []interface{}{&Server{}, &Config{}, Logger(&stdLogger{}), &Cache{}}
+}
+
+// A reader that fails for some keys
+type failingReader struct{}
+
+func (failingReader) Read(key string) (interface{}, error) {
+
+	if key == "app.broken" {
+		return nil, errors.New("connection refused")
+	}
+
+	return nil, inj.ErrNotFound
+}
+
+func datasources() []inj.DatasourceReader {
+	return []inj.DatasourceReader{
+		failingReader{},
+		inj.NewMemoryDatasource(map[string]interface{}{
+			"app.name":       []byte("test"),
+			"app.debug":      "true",
+			"app.workers":    float64(4),
+			"app.ratio":      "0.5",
+			"app.limit":      12,
+			"app.level":      "debug",
+			"app.retries":    300,
+			"app.grace":      "30",
+			"app.token":      "hunter2",
+			"db.host":        "localhost",
+			"db.port_number": "5432",
+			"db.timeout":     "5s",
+			"db.tags":        "a, b",
+			"db.ignored":     "changed",
+			"size":           "64",
+			"server.port":    8080,
+		}),
+	}
+}
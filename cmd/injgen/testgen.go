@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// The test that checks generated wiring against the graph. The comparison
+// follows pointers, but values that are nodes must refer to the same node in
+// both wirings.
+var testTemplate = template.Must(template.New("test").Parse(`// Code generated by injgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/yourheropaul/inj"
+)
+
+// The wiring generated by injgen must match the wiring of an inj graph
+func Test{{.Test}}(t *testing.T) {
+
+	readers := {{if .Datasources}}{{.Datasources}}(){{else}}[]inj.DatasourceReader{}{{end}}
+
+	generated := {{.Providers}}()
+	errs := {{.Function}}(generated, readers...)
+
+	reflective := {{.Providers}}()
+	g := inj.NewGraph()
+
+	for _, r := range readers {
+		g.AddDatasource(r)
+	}
+
+	g.Provide(reflective...)
+
+	got := make([]string, len(errs))
+
+	for i, e := range errs {
+		got[i] = e.Error()
+	}
+
+	_, want := g.Assert()
+
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("The generated wiring reported %q, but the graph reported %q", got, want)
+	}
+
+	c := injComparison{t, generated, reflective}
+
+	for i := range generated {
+
+		a, b := reflect.ValueOf(generated[i]), reflect.ValueOf(reflective[i])
+
+		if a.Kind() == reflect.Ptr && !a.IsNil() && !b.IsNil() {
+			a, b = a.Elem(), b.Elem()
+		}
+
+		c.compare(fmt.Sprintf("%T", generated[i]), a, b, 0)
+	}
+}
+
+type injComparison struct {
+	t                     *testing.T
+	generated, reflective []interface{}
+}
+
+// Compare a value from the generated wiring with the same value from the graph
+func (c injComparison) compare(path string, a, b reflect.Value, depth int) {
+
+	// Give up on cycles
+	if depth > 8 {
+		return
+	}
+
+	if a.Kind() == reflect.Interface {
+
+		if a.IsNil() || b.IsNil() {
+			c.compareNil(path, a, b)
+			return
+		}
+
+		a, b = a.Elem(), b.Elem()
+	}
+
+	if a.Type() != b.Type() {
+		c.t.Errorf("%s is a %s in the generated wiring, and a %s in the graph", path, a.Type(), b.Type())
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan:
+		if a.IsNil() || b.IsNil() {
+			c.compareNil(path, a, b)
+			return
+		}
+
+		i, j := injNodeIndex(c.generated, a), injNodeIndex(c.reflective, b)
+
+		if i != j {
+			c.t.Errorf("%s refers to node %d in the generated wiring, and node %d in the graph", path, i, j)
+			return
+		}
+
+		if i >= 0 {
+			return
+		}
+
+		switch a.Kind() {
+		case reflect.Ptr:
+			c.compare(path, a.Elem(), b.Elem(), depth+1)
+		case reflect.Map:
+			c.compareMaps(path, a, b, depth)
+		}
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			c.compare(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i), depth)
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && (a.IsNil() || b.IsNil()) {
+			c.compareNil(path, a, b)
+			return
+		}
+
+		if a.Len() != b.Len() {
+			c.t.Errorf("%s has %d elements in the generated wiring, and %d in the graph", path, a.Len(), b.Len())
+			return
+		}
+
+		for i := 0; i < a.Len(); i++ {
+			c.compare(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), depth)
+		}
+	case reflect.Func:
+		c.compareNil(path, a, b)
+	case reflect.UnsafePointer:
+	default:
+		if fmt.Sprint(a) != fmt.Sprint(b) {
+			c.t.Errorf("%s is %v in the generated wiring, and %v in the graph", path, a, b)
+		}
+	}
+}
+
+func (c injComparison) compareNil(path string, a, b reflect.Value) {
+
+	if a.IsNil() != b.IsNil() {
+		c.t.Errorf("%s is only nil in one wiring (generated: %t)", path, a.IsNil())
+	}
+}
+
+func (c injComparison) compareMaps(path string, a, b reflect.Value, depth int) {
+
+	if a.Len() != b.Len() {
+		c.t.Errorf("%s has %d keys in the generated wiring, and %d in the graph", path, a.Len(), b.Len())
+		return
+	}
+
+	keys := make(map[string]reflect.Value)
+
+	for _, k := range b.MapKeys() {
+		keys[fmt.Sprint(k)] = k
+	}
+
+	for _, k := range a.MapKeys() {
+
+		kb, exists := keys[fmt.Sprint(k)]
+
+		if !exists {
+			c.t.Errorf("%s[%v] is only in the generated wiring", path, k)
+			continue
+		}
+
+		c.compare(fmt.Sprintf("%s[%v]", path, k), a.MapIndex(k), b.MapIndex(kb), depth+1)
+	}
+}
+
+// The index of the node that a value refers to, or -1
+func injNodeIndex(nodes []interface{}, v reflect.Value) int {
+
+	for i, n := range nodes {
+
+		nv := reflect.ValueOf(n)
+
+		if nv.IsValid() && nv.Type() == v.Type() && nv.Pointer() == v.Pointer() {
+			return i
+		}
+	}
+
+	return -1
+}
+`))
+
+// Generate the test that checks the wiring against the graph
+func generateTest(pkg string, s spec) ([]byte, error) {
+
+	var b bytes.Buffer
+
+	err := testTemplate.Execute(&b, map[string]string{
+		"Package":     pkg,
+		"Test":        "Injgen" + strings.ToUpper(s.function[:1]) + s.function[1:],
+		"Providers":   s.providers,
+		"Datasources": s.datasources,
+		"Function":    s.function,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The import path of the inj package
+const injPath = "github.com/yourheropaul/inj"
+
+// Struct tag flags and validation rules understood by inj
+var (
+	tagFlags = map[string]bool{"unexported": true, "prefix": true, "secret": true}
+	tagRules = map[string]bool{"min": true, "max": true, "oneof": true, "regexp": true}
+)
+
+// A problem found in a package
+type diagnostic struct {
+	pos     token.Position
+	message string
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.pos, d.message)
+}
+
+// Options that change which problems are reported
+type options struct {
+	variadic bool
+	json     bool
+}
+
+type checker struct {
+	fset        *token.FileSet
+	pkg         *types.Package
+	info        *types.Info
+	opts        options
+	diagnostics []diagnostic
+}
+
+// Check the files of a type-checked package for problems with inj wiring
+func check(fset *token.FileSet, pkg *types.Package, files []*ast.File, info *types.Info, opts options) []diagnostic {
+
+	c := &checker{fset: fset, pkg: pkg, info: info, opts: opts}
+
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+
+			switch node := n.(type) {
+			case *ast.StructType:
+				c.checkStruct(node)
+			case *ast.CallExpr:
+				c.checkCall(node)
+			}
+
+			return true
+		})
+	}
+
+	sort.Slice(c.diagnostics, func(i, j int) bool {
+
+		a, b := c.diagnostics[i].pos, c.diagnostics[j].pos
+
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return c.diagnostics
+}
+
+// Format a type, qualified by package name unless it's in the checked package
+func (c *checker) typeString(t types.Type) string {
+
+	return types.TypeString(t, func(p *types.Package) string {
+
+		if p == c.pkg {
+			return ""
+		}
+
+		return p.Name()
+	})
+}
+
+func (c *checker) report(pos token.Pos, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, diagnostic{c.fset.Position(pos), fmt.Sprintf(format, args...)})
+}
+
+///////////////////////////////////////////////
+// Struct tags
+///////////////////////////////////////////////
+
+func (c *checker) checkStruct(s *ast.StructType) {
+
+	for _, field := range s.Fields.List {
+
+		if field.Tag == nil {
+			continue
+		}
+
+		raw, err := strconv.Unquote(field.Tag.Value)
+
+		if err != nil || !strings.Contains(raw, "inj") {
+			continue
+		}
+
+		if err := validateStructTag(raw); err != nil {
+			c.report(field.Tag.Pos(), "malformed struct tag %s: %s", field.Tag.Value, err)
+			continue
+		}
+
+		value, ok := reflect.StructTag(raw).Lookup("inj")
+
+		if !ok {
+			continue
+		}
+
+		flags := c.checkTagValue(field, value)
+
+		for _, name := range fieldNames(field) {
+			if !ast.IsExported(name) && !flags["unexported"] {
+				c.report(field.Pos(), "field %s is unexported, so its inj tag is ignored (add the unexported flag to set it)", name)
+			}
+		}
+
+		if flags["prefix"] {
+			if _, ok := c.underlying(field.Type).(*types.Struct); !ok {
+				c.report(field.Tag.Pos(), "the prefix flag can only be used on struct fields")
+			}
+		}
+	}
+}
+
+// Check the value of an inj tag, and return the flags it contains
+func (c *checker) checkTagValue(field *ast.Field, value string) map[string]bool {
+
+	flags := make(map[string]bool)
+	parts := strings.Split(value, ",")
+
+	// Tags that start with a comma have no datasource paths, so anything
+	// that isn't a flag or a rule is an error
+	pathless := len(parts) > 1 && parts[0] == ""
+
+	for i := 0; i < len(parts); i++ {
+
+		part := parts[i]
+
+		// A regexp can contain commas, so it takes up the rest of the tag
+		if strings.HasPrefix(part, "regexp=") {
+			part = strings.Join(parts[i:], ",")
+			i = len(parts)
+		}
+
+		if part == "" {
+			continue
+		}
+
+		if tagFlags[part] {
+			flags[part] = true
+			continue
+		}
+
+		if part == "nonzero" {
+			continue
+		}
+
+		if strings.IndexFunc(part, unicode.IsSpace) >= 0 {
+			c.report(field.Tag.Pos(), "malformed inj tag: datasource path %q contains whitespace", part)
+			continue
+		}
+
+		eq := strings.Index(part, "=")
+
+		if eq < 0 {
+			if pathless {
+				c.report(field.Tag.Pos(), "unknown flag %s in inj tag", part)
+			}
+			continue
+		}
+
+		name, arg := part[:eq], part[eq+1:]
+
+		if !tagRules[name] {
+			c.report(field.Tag.Pos(), "unknown rule %s= in inj tag", name)
+			continue
+		}
+
+		if err := c.checkRule(field, name, arg); err != nil {
+			c.report(field.Tag.Pos(), "malformed inj tag: %s", err)
+		}
+	}
+
+	return flags
+}
+
+// Check the argument of a validation rule
+func (c *checker) checkRule(field *ast.Field, name, arg string) error {
+
+	switch name {
+	case "min", "max":
+		if t := c.info.TypeOf(field.Type); t != nil && !hasBounds(t) {
+			return fmt.Errorf("%s= can't be applied to %s", name, t)
+		}
+
+		if t := c.info.TypeOf(field.Type); t != nil && t.String() == "time.Duration" {
+			if _, err := time.ParseDuration(arg); err != nil {
+				return fmt.Errorf("%s=%s isn't a duration", name, arg)
+			}
+		} else if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("%s=%s isn't a number", name, arg)
+		}
+
+	case "oneof":
+		if arg == "" {
+			return fmt.Errorf("oneof= has no options")
+		}
+
+	case "regexp":
+		if _, err := regexp.Compile(arg); err != nil {
+			return fmt.Errorf("regexp=%s: %s", arg, err)
+		}
+	}
+
+	return nil
+}
+
+// Returns true if min and max rules can be applied to a type: numbers (and
+// durations) are compared by value, and strings, slices, arrays and maps by
+// length
+func hasBounds(t types.Type) bool {
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&(types.IsNumeric|types.IsString) != 0
+	case *types.Slice, *types.Array, *types.Map:
+		return true
+	}
+
+	return false
+}
+
+// The names of a field, including the type name of an embedded field
+func fieldNames(field *ast.Field) []string {
+
+	if len(field.Names) > 0 {
+
+		names := make([]string, len(field.Names))
+
+		for i, n := range field.Names {
+			names[i] = n.Name
+		}
+
+		return names
+	}
+
+	t := field.Type
+
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+
+	switch n := t.(type) {
+	case *ast.Ident:
+		return []string{n.Name}
+	case *ast.SelectorExpr:
+		return []string{n.Sel.Name}
+	}
+
+	return nil
+}
+
+func (c *checker) underlying(e ast.Expr) types.Type {
+
+	if t := c.info.TypeOf(e); t != nil {
+		return t.Underlying()
+	}
+
+	return nil
+}
+
+// Validate the syntax of a struct tag, in the same way as go vet
+func validateStructTag(tag string) error {
+
+	for tag != "" {
+
+		i := 0
+
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+
+		tag = tag[i:]
+
+		if tag == "" {
+			break
+		}
+
+		i = 0
+
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+
+		if i == 0 {
+			return fmt.Errorf("bad syntax for struct tag key")
+		}
+
+		if i+1 >= len(tag) || tag[i] != ':' {
+			return fmt.Errorf("bad syntax for struct tag pair")
+		}
+
+		if tag[i+1] != '"' {
+			return fmt.Errorf("bad syntax for struct tag value")
+		}
+
+		tag = tag[i+1:]
+
+		// Find the end of the quoted value
+		i = 1
+
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+
+		if i >= len(tag) {
+			return fmt.Errorf("bad syntax for struct tag value")
+		}
+
+		if _, err := strconv.Unquote(tag[:i+1]); err != nil {
+			return fmt.Errorf("bad syntax for struct tag value")
+		}
+
+		tag = tag[i+1:]
+	}
+
+	return nil
+}
+
+///////////////////////////////////////////////
+// Calls to Provide() and Inject()
+///////////////////////////////////////////////
+
+func (c *checker) checkCall(call *ast.CallExpr) {
+
+	name := injFunction(c.info, call)
+
+	switch name {
+	case "Provide", "ProvideContext":
+
+		args := call.Args
+
+		if name == "ProvideContext" && len(args) > 0 {
+			args = args[1:]
+		}
+
+		// Values spread from a slice can't be checked
+		if call.Ellipsis.IsValid() {
+			return
+		}
+
+		for _, arg := range args {
+
+			t := c.info.TypeOf(arg)
+
+			if t == nil {
+				continue
+			}
+
+			if _, ok := t.Underlying().(*types.Struct); ok && hasInjTags(t, make(map[types.Type]bool)) {
+				c.report(arg.Pos(), "%s is passed to %s by value, so its inj fields can never be set (pass a pointer)", c.typeString(t), name)
+			}
+		}
+
+	case "Inject":
+
+		if len(call.Args) == 0 {
+			return
+		}
+
+		t := c.info.TypeOf(call.Args[0])
+
+		if t == nil {
+			return
+		}
+
+		switch u := t.Underlying().(type) {
+		case *types.Signature:
+			if u.Variadic() && c.opts.variadic {
+				c.report(call.Args[0].Pos(), "variadic function passed to Inject: its variadic arguments depend on the contents of the graph")
+			}
+		case *types.Interface:
+			// Can't be checked until runtime
+		default:
+			c.report(call.Args[0].Pos(), "Inject requires a function, not %s", c.typeString(t))
+		}
+	}
+}
+
+// Get the name of the inj function or Grapher method being called, if any
+func injFunction(info *types.Info, call *ast.CallExpr) string {
+
+	var id *ast.Ident
+
+	fun := call.Fun
+
+	for {
+		p, ok := fun.(*ast.ParenExpr)
+
+		if !ok {
+			break
+		}
+
+		fun = p.X
+	}
+
+	switch fn := fun.(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	default:
+		return ""
+	}
+
+	f, ok := info.Uses[id].(*types.Func)
+
+	if !ok || f.Pkg() == nil || !isInjPath(f.Pkg().Path()) {
+		return ""
+	}
+
+	return f.Name()
+}
+
+// The inj package may be vendored
+func isInjPath(path string) bool {
+	return path == injPath || strings.HasSuffix(path, "/vendor/"+injPath)
+}
+
+// Returns true if a struct has fields with inj tags, directly or in nested
+// struct fields (which inj searches)
+func hasInjTags(t types.Type, seen map[types.Type]bool) bool {
+
+	if seen[t] {
+		return false
+	}
+
+	seen[t] = true
+
+	s, ok := t.Underlying().(*types.Struct)
+
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < s.NumFields(); i++ {
+
+		if _, ok := reflect.StructTag(s.Tag(i)).Lookup("inj"); ok {
+			return true
+		}
+
+		if hasInjTags(s.Field(i).Type(), seen) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// An importer that type-checks the inj package from the source in this
+// repository, and everything else from the standard library
+type testImporter struct {
+	fset  *token.FileSet
+	std   types.Importer
+	cache map[string]*types.Package
+}
+
+func (i *testImporter) Import(path string) (*types.Package, error) {
+
+	if path != injPath {
+		return i.std.Import(path)
+	}
+
+	if p, exists := i.cache[path]; exists {
+		return p, nil
+	}
+
+	pkg, err := build.ImportDir(filepath.Join("..", ".."), 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := parseFiles(i.fset, pkg.Dir, pkg.GoFiles)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := &types.Config{Importer: i.std}
+	p, err := config.Check(path, i.fset, files, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i.cache[path] = p
+
+	return p, nil
+}
+
+var wantPattern = regexp.MustCompile(`// want (".*")\s*$`)
+
+// Every problem in the test data should be reported, and nothing else
+func Test_Check(t *testing.T) {
+
+	filename := filepath.Join("testdata", "wiring", "wiring.go")
+	fset := token.NewFileSet()
+
+	imp := &testImporter{fset, importer.ForCompiler(fset, "source", nil), make(map[string]*types.Package)}
+
+	diagnostics, err := checkFiles(fset, "wiring", []string{filename}, imp, options{variadic: true})
+
+	if err != nil {
+		t.Fatalf("checkFiles: %s", err)
+	}
+
+	// Find the expected problems
+	b, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+
+	want := make(map[int]*regexp.Regexp)
+
+	for i, line := range strings.Split(string(b), "\n") {
+		if m := wantPattern.FindStringSubmatch(line); m != nil {
+
+			pattern, err := strconv.Unquote(m[1])
+
+			if err != nil {
+				t.Fatalf("Line %d: bad want comment %s", i+1, m[1])
+			}
+
+			want[i+1] = regexp.MustCompile(pattern)
+		}
+	}
+
+	for _, d := range diagnostics {
+
+		re, exists := want[d.pos.Line]
+
+		if !exists {
+			t.Errorf("Unexpected problem %s", d)
+			continue
+		}
+
+		if !re.MatchString(d.message) {
+			t.Errorf("Line %d: %q doesn't match %s", d.pos.Line, d.message, re)
+		}
+
+		delete(want, d.pos.Line)
+	}
+
+	for line, re := range want {
+		t.Errorf("Line %d: expected a problem matching %s", line, re)
+	}
+}
+
+// Variadic functions are only reported when asked
+func Test_CheckVariadicOption(t *testing.T) {
+
+	filename := filepath.Join("testdata", "wiring", "wiring.go")
+	fset := token.NewFileSet()
+
+	imp := &testImporter{fset, importer.ForCompiler(fset, "source", nil), make(map[string]*types.Package)}
+
+	diagnostics, err := checkFiles(fset, "wiring", []string{filename}, imp, options{})
+
+	if err != nil {
+		t.Fatalf("checkFiles: %s", err)
+	}
+
+	for _, d := range diagnostics {
+		if strings.Contains(d.message, "variadic") {
+			t.Errorf("Unexpected problem %s", d)
+		}
+	}
+}
+
+// Struct tag syntax should be checked in the same way as go vet
+func Test_ValidateStructTag(t *testing.T) {
+
+	for tag, valid := range map[string]bool{
+		`inj:""`:                 true,
+		`inj:"a,b" json:"x"`:     true,
+		`inj:"a\"b"`:             true,
+		`inj: "a"`:               false,
+		`inj:a`:                  false,
+		`inj:"a`:                 false,
+		`:"a"`:                   false,
+		`inj`:                    false,
+		`inj:"a" json:"b" x:"y"`: true,
+	} {
+		if err := validateStructTag(tag); (err == nil) != valid {
+			t.Errorf("validateStructTag(%s): expected valid to be %t, got %v", tag, valid, err)
+		}
+	}
+}
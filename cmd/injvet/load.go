@@ -0,0 +1,362 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+///////////////////////////////////////////////
+// Directories
+///////////////////////////////////////////////
+
+// Check package directories, and return an exit status
+func runDirs(patterns []string, tests bool, opts options) int {
+
+	dirs, err := expandDirs(patterns)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+
+	for _, dir := range dirs {
+
+		diagnostics, err := checkDir(dir, tests, opts)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, d := range diagnostics {
+			fmt.Fprintln(os.Stderr, d)
+
+			if status == 0 {
+				status = 2
+			}
+		}
+	}
+
+	return status
+}
+
+// Expand patterns ending in /... to every directory below them that
+// contains Go files (skipping testdata, vendor and hidden directories)
+func expandDirs(patterns []string) ([]string, error) {
+
+	dirs := make([]string, 0)
+
+	for _, pattern := range patterns {
+
+		if !strings.HasSuffix(pattern, "/...") && pattern != "..." {
+			dirs = append(dirs, pattern)
+			continue
+		}
+
+		root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+
+		if root == "" {
+			root = "."
+		}
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() {
+				return nil
+			}
+
+			name := info.Name()
+
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+
+			if matches, _ := filepath.Glob(filepath.Join(path, "*.go")); len(matches) > 0 {
+				dirs = append(dirs, path)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dirs, nil
+}
+
+// Parse and type-check the package in a directory, then check it
+func checkDir(dir string, tests bool, opts options) ([]diagnostic, error) {
+
+	pkg, err := build.ImportDir(dir, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filenames := pkg.GoFiles
+
+	if tests {
+		filenames = append(filenames, pkg.TestGoFiles...)
+	}
+
+	for i, name := range filenames {
+		filenames[i] = filepath.Join(dir, name)
+	}
+
+	fset := token.NewFileSet()
+
+	return checkFiles(fset, pkg.ImportPath, filenames, importer.ForCompiler(fset, "source", nil), opts)
+}
+
+// Parse and type-check some files, then check them. Type errors are ignored,
+// so that as much as possible is checked.
+func checkFiles(fset *token.FileSet, path string, filenames []string, imp types.Importer, opts options) ([]diagnostic, error) {
+
+	files, err := parseFiles(fset, "", filenames)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+
+	config := &types.Config{
+		Importer: imp,
+		Error:    func(error) {},
+	}
+
+	pkg, _ := config.Check(path, fset, files, info)
+
+	return check(fset, pkg, files, info, opts), nil
+}
+
+// Parse some files, relative to a directory
+func parseFiles(fset *token.FileSet, dir string, filenames []string) ([]*ast.File, error) {
+
+	files := make([]*ast.File, 0, len(filenames))
+
+	for _, name := range filenames {
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+///////////////////////////////////////////////
+// go vet
+///////////////////////////////////////////////
+
+// The configuration that go vet passes to a vet tool
+type vetConfig struct {
+	ID          string
+	ImportPath  string
+	Compiler    string
+	GoFiles     []string
+	ImportMap   map[string]string
+	PackageFile map[string]string
+	VetxOnly    bool
+	VetxOutput  string
+	Stdout      string
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// Check a package described by a go vet configuration file, and
+// return an exit status
+func runVet(filename string, opts options) int {
+
+	b, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var cfg vetConfig
+
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't parse %s: %s\n", filename, err)
+		return 1
+	}
+
+	// There are no facts to pass between packages, but go vet
+	// expects the output to exist
+	if cfg.VetxOutput != "" {
+		if err := ioutil.WriteFile(cfg.VetxOutput, nil, 0666); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if cfg.VetxOnly {
+		return 0
+	}
+
+	fset := token.NewFileSet()
+
+	// Import the compiled packages that go vet provides
+	compiled := importer.ForCompiler(fset, cfg.Compiler, func(path string) (io.ReadCloser, error) {
+
+		file, exists := cfg.PackageFile[path]
+
+		if !exists {
+			return nil, fmt.Errorf("No package file for %s", path)
+		}
+
+		return os.Open(file)
+	})
+
+	imp := importerFunc(func(importPath string) (*types.Package, error) {
+
+		path, exists := cfg.ImportMap[importPath]
+
+		if !exists {
+			return nil, fmt.Errorf("Can't resolve import %s", importPath)
+		}
+
+		return compiled.Import(path)
+	})
+
+	diagnostics, err := checkFiles(fset, cfg.ImportPath, cfg.GoFiles, imp, opts)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// In JSON mode, go vet reports the problems itself. Newer versions
+	// ask for the output in a file.
+	if opts.json {
+
+		out := os.Stdout
+
+		if cfg.Stdout != "" {
+
+			if out, err = os.Create(cfg.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+
+			defer out.Close()
+		}
+
+		printJSON(out, cfg.ID, diagnostics)
+
+		return 0
+	}
+
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+
+	if len(diagnostics) > 0 {
+		return 2
+	}
+
+	return 0
+}
+
+// Print problems in the JSON format used by go vet, which is keyed by
+// package and then by analyzer
+func printJSON(w io.Writer, id string, diagnostics []diagnostic) {
+
+	type jsonDiagnostic struct {
+		Posn    string `json:"posn"`
+		Message string `json:"message"`
+	}
+
+	tree := make(map[string]map[string][]jsonDiagnostic)
+
+	if len(diagnostics) > 0 {
+
+		list := make([]jsonDiagnostic, len(diagnostics))
+
+		for i, d := range diagnostics {
+			list[i] = jsonDiagnostic{d.pos.String(), d.message}
+		}
+
+		tree[id] = map[string][]jsonDiagnostic{"injvet": list}
+	}
+
+	b, _ := json.MarshalIndent(tree, "", "\t")
+	w.Write(append(b, '\n'))
+}
+
+// go vet identifies vet tools by their version, which must change
+// whenever the tool does
+func printVersion() {
+
+	h := sha256.New()
+
+	if exe, err := os.Executable(); err == nil {
+		if f, err := os.Open(exe); err == nil {
+			io.Copy(h, f)
+			f.Close()
+		}
+	}
+
+	fmt.Printf("injvet version devel buildID=%x\n", h.Sum(nil))
+}
+
+// go vet asks vet tools which flags they support
+func printFlags(fs *flag.FlagSet) {
+
+	type jsonFlag struct {
+		Name  string
+		Bool  bool
+		Usage string
+	}
+
+	flags := make([]jsonFlag, 0)
+
+	fs.VisitAll(func(f *flag.Flag) {
+
+		switch f.Name {
+		case "V", "flags", "json", "tests":
+			return
+		}
+
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, jsonFlag{f.Name, ok && b.IsBoolFlag(), f.Usage})
+	})
+
+	b, _ := json.MarshalIndent(flags, "", "\t")
+	os.Stdout.Write(append(b, '\n'))
+}
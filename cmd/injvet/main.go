@@ -0,0 +1,79 @@
+/*
+Command injvet checks the inj wiring of Go packages without running them.
+
+It reports:
+
+  - malformed struct tags on fields with inj tags, and malformed inj tag
+    values (unknown or invalid validation rules, and datasource paths that
+    contain whitespace)
+  - inj tags on unexported fields that don't have the unexported flag, which
+    inj ignores
+  - the prefix flag on fields that aren't structs
+  - structs with inj tags passed to Provide() by value, whose fields can
+    never be set
+  - calls to Inject() with arguments that aren't functions (and, with the
+    -variadic flag, variadic functions)
+
+It can be run on package directories (a path ending in /... includes every
+directory below it):
+
+	injvet ./...
+
+or by go vet, which type-checks packages much more quickly:
+
+	go vet -vettool=$(which injvet) ./...
+
+injvet exits with status 2 if it finds any problems, and 1 if it can't
+check a package.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+
+	var opts options
+
+	fs := flag.NewFlagSet("injvet", flag.ExitOnError)
+	fs.BoolVar(&opts.variadic, "variadic", false, "report variadic functions passed to Inject")
+	tests := fs.Bool("tests", false, "also check test files (when not run by go vet)")
+
+	// Flags used by go vet to query the tool
+	version := fs.String("V", "", "print version and exit")
+	describe := fs.Bool("flags", false, "print flags as JSON and exit")
+	fs.BoolVar(&opts.json, "json", false, "print problems as JSON (when run by go vet)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: injvet [flags] [directories]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(os.Args[1:])
+
+	switch {
+	case *version != "":
+		printVersion()
+		return
+	case *describe:
+		printFlags(fs)
+		return
+	}
+
+	args := fs.Args()
+
+	// go vet passes a single configuration file
+	if len(args) == 1 && strings.HasSuffix(args[0], ".cfg") {
+		os.Exit(runVet(args[0], opts))
+	}
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	os.Exit(runDirs(args, *tests, opts))
+}
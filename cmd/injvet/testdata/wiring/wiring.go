@@ -0,0 +1,67 @@
+package wiring
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourheropaul/inj"
+)
+
+type Logger interface {
+	Log(string)
+}
+
+type Config struct {
+	Log      Logger        `inj:""`
+	Port     int           `inj:"server.port,min=1,max=65535"`
+	Timeout  time.Duration `inj:"timeout,max=1m"`
+	Level    string        `inj:"log.level,oneof=debug|info"`
+	Password string        `inj:"db.password,secret,nonzero"`
+	internal Logger        `inj:",unexported"`
+	DB       struct {
+		Host string
+	} `inj:"db,prefix"`
+	Untagged string
+}
+
+type Broken struct {
+	Port    int             `inj:"server.port,min=one"`       // want "min=one isn't a number"
+	Timeout time.Duration   `inj:"timeout,max=1"`             // want "max=1 isn't a duration"
+	Name    string          `inj:"name,regexp=(["`            // want "regexp=\\(\\[: error parsing regexp"
+	Level   string          `inj:"level,oneof="`              // want "oneof= has no options"
+	Typo    string          `inj:"level,mx=2"`                // want "unknown rule mx="
+	Flagged string          `inj:",secert"`                   // want "unknown flag secert in inj tag"
+	Code    string          `inj:"code,regexp=^[a-z]{2,5}($"` // want "regexp=\\^\\[a-z\\]\\{2,5\\}\\(\\$: error parsing regexp"
+	Valid   string          `inj:"code,regexp=^[a-z]{2,5}$"`
+	Flag    bool            `inj:"flag,min=1"` // want "min= can't be applied to bool"
+	Spaces  string          `inj:"some path"`  // want "datasource path \"some path\" contains whitespace"
+	Syntax  string          `inj: "x"`         // want "malformed struct tag"
+	Prefix  int             `inj:"p,prefix"`   // want "the prefix flag can only be used on struct fields"
+	hidden  Logger          `inj:""`           // want "field hidden is unexported"
+	Nested  struct{ V int } `inj:"nested"`
+}
+
+type Nested struct {
+	Inner struct {
+		Log Logger `inj:""`
+	}
+}
+
+func Wire(ctx context.Context, g inj.Grapher) {
+
+	inj.Provide(&Config{}, Config{}) // want "Config is passed to Provide by value"
+	inj.Provide(Nested{})            // want "Nested is passed to Provide by value"
+	inj.Provide(time.Second, "ok")
+	g.ProvideContext(ctx, Config{}) // want "Config is passed to ProvideContext by value"
+
+	inj.Inject(func(l Logger) {})
+	inj.Inject(func(xs ...Logger) {}) // want "variadic function passed to Inject"
+	inj.Inject(42)                    // want "Inject requires a function, not int"
+	g.Inject(Config{})                // want "Inject requires a function, not Config"
+
+	var anything interface{}
+	inj.Inject(anything)
+
+	args := []interface{}{&Config{}}
+	inj.Provide(args...)
+}
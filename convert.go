@@ -0,0 +1,456 @@
+package inj
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// A registered conversion function
+type converter struct {
+	from reflect.Type
+	to   reflect.Type
+	fn   reflect.Value
+}
+
+// Register a function that converts datasource values of one type into
+// another. The function must have the signature func(From) (To, error) or
+// func(From) To. Converters are tried before the built-in conversions, most
+// recently registered first, whenever a datasource value isn't directly
+// assignable to a dependency. As with AddDatasource(), the graph is
+// re-Provided once the converter is added.
+//
+//  g.AddConverter(func(s string) (Level, error) {
+//      return ParseLevel(s)
+//  })
+func (g *graph) AddConverter(fn interface{}) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v := reflect.ValueOf(fn)
+
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("Converter is %s, not a function", v.Kind())
+	}
+
+	t := v.Type()
+
+	if t.NumIn() != 1 || t.IsVariadic() || t.NumOut() < 1 || t.NumOut() > 2 ||
+		(t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("Converter %s must have the signature func(From) (To, error)", t)
+	}
+
+	g.converters = append([]converter{{t.In(0), t.Out(0), v}}, g.converters...)
+
+	// Previously unmet dependencies may now be convertible
+	return g.provide()
+}
+
+// Allow numeric conversions that lose a value's fraction, wrap it around or
+// change its sign, as Go's own conversions do (so 16.01 becomes 16 for an
+// int field). By default they're reported by Assert() instead. Numbers still
+// can't become durations, since they have no unit. The setting takes effect
+// the next time the graph is connected.
+func (g *graph) SetLossyConversion(allow bool) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lossy = allow
+}
+
+// Convert a value into a given type, using any registered converters
+// and the built-in conversions.
+//
+// The built-in conversions parse strings into numbers, booleans, durations,
+// times (RFC 3339 or yyyy-mm-dd), URLs and any type that implements
+// encoding.TextUnmarshaler (such as net.IP); they also convert between
+// numeric types, and convert slices, maps, structs and pointers element by
+// element. Strings are split on commas to make slices, and maps are used to
+// populate structs by (case-insensitive) field name, and strings convert to
+// and from byte and rune slices. Numbers that would lose a fraction or
+// overflow aren't converted (unless SetLossyConversion() allows it), and
+// neither are numbers that would become durations (since they have no unit).
+func (g *graph) convert(value reflect.Value, to reflect.Type) (reflect.Value, error) {
+
+	// Interfaces are unwrapped, so the underlying value can be converted
+	for value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() {
+		return value, fmt.Errorf("Can't convert a nil value to %s", to)
+	}
+
+	from := value.Type()
+
+	if from.AssignableTo(to) {
+		return value, nil
+	}
+
+	for _, c := range g.converters {
+		if from.AssignableTo(c.from) && c.to.AssignableTo(to) {
+
+			out := c.fn.Call([]reflect.Value{value})
+
+			if len(out) == 2 && !out[1].IsNil() {
+				return value, out[1].Interface().(error)
+			}
+
+			return out[0], nil
+		}
+	}
+
+	// Strings may need to be parsed
+	if from.Kind() == reflect.String {
+		if v, ok, err := g.convertFromString(value.String(), to); ok {
+			return v, err
+		}
+	}
+
+	switch to.Kind() {
+	case reflect.Ptr:
+		v, err := g.convert(value, to.Elem())
+
+		if err != nil {
+			return value, err
+		}
+
+		ptr := reflect.New(to.Elem())
+		ptr.Elem().Set(v)
+
+		return ptr, nil
+	case reflect.String:
+		if isByteOrRuneSlice(from) {
+			return value.Convert(to), nil
+		}
+
+		if isNumeric(from) || from.Kind() == reflect.Bool {
+			v := reflect.New(to).Elem()
+			v.SetString(fmt.Sprint(value.Interface()))
+			return v, nil
+		}
+	case reflect.Slice:
+		if from.Kind() == reflect.Slice || from.Kind() == reflect.Array {
+			return g.convertSlice(value, to)
+		}
+	case reflect.Map:
+		if from.Kind() == reflect.Map {
+			return g.convertMap(value, to)
+		}
+	case reflect.Struct:
+		if from.Kind() == reflect.Map && from.Key().Kind() == reflect.String {
+			return g.convertStruct(value, to)
+		}
+	}
+
+	if isNumeric(from) && isNumeric(to) {
+		return convertNumber(value, to, g.lossy)
+	}
+
+	if from.ConvertibleTo(to) && from.Kind() == to.Kind() {
+		return value.Convert(to), nil
+	}
+
+	return value, fmt.Errorf("Can't convert %s to %s", from, to)
+}
+
+// Parse a string into a type. The final return value is false if
+// the type can't be parsed from a string at all.
+func (g *graph) convertFromString(s string, to reflect.Type) (reflect.Value, bool, error) {
+
+	v := reflect.New(to)
+
+	switch {
+	case to == urlType:
+		u, err := url.Parse(s)
+
+		if err != nil {
+			return v, true, err
+		}
+
+		return reflect.ValueOf(*u), true, nil
+	case to == timeType:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return reflect.ValueOf(t), true, nil
+			}
+		}
+
+		return v, true, fmt.Errorf("Can't parse %s as a time", s)
+	case v.Type().Implements(textUnmarshalerType):
+		err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		return v.Elem(), true, err
+	case isByteOrRuneSlice(to):
+		return reflect.ValueOf(s).Convert(to), true, nil
+	case to.Kind() == reflect.Slice:
+		parts := strings.Split(s, ",")
+
+		if s == "" {
+			parts = []string{}
+		}
+
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		v, err := g.convertSlice(reflect.ValueOf(parts), to)
+		return v, true, err
+	}
+
+	if to == durationType || isNumeric(to) || to.Kind() == reflect.Bool || to.Kind() == reflect.String {
+		v, err := convertString(s, to)
+		return v, true, err
+	}
+
+	return v, false, nil
+}
+
+func (g *graph) convertSlice(value reflect.Value, to reflect.Type) (reflect.Value, error) {
+
+	slice := reflect.MakeSlice(to, value.Len(), value.Len())
+
+	for i := 0; i < value.Len(); i++ {
+
+		v, err := g.convert(value.Index(i), to.Elem())
+
+		if err != nil {
+			return value, fmt.Errorf("Element %d: %s", i, err)
+		}
+
+		slice.Index(i).Set(v)
+	}
+
+	return slice, nil
+}
+
+func (g *graph) convertMap(value reflect.Value, to reflect.Type) (reflect.Value, error) {
+
+	m := reflect.MakeMapWithSize(to, value.Len())
+
+	for _, key := range value.MapKeys() {
+
+		k, err := g.convert(key, to.Key())
+
+		if err != nil {
+			return value, fmt.Errorf("Key %v: %s", key, err)
+		}
+
+		v, err := g.convert(value.MapIndex(key), to.Elem())
+
+		if err != nil {
+			return value, fmt.Errorf("Key %v: %s", key, err)
+		}
+
+		m.SetMapIndex(k, v)
+	}
+
+	return m, nil
+}
+
+func (g *graph) convertStruct(value reflect.Value, to reflect.Type) (reflect.Value, error) {
+
+	s := reflect.New(to).Elem()
+
+	for _, key := range value.MapKeys() {
+
+		name := key.String()
+
+		f := s.FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+
+		// Unknown keys are ignored
+		if !f.IsValid() || !f.CanSet() {
+			continue
+		}
+
+		v, err := g.convert(value.MapIndex(key), f.Type())
+
+		if err != nil {
+			return value, fmt.Errorf("Field %s: %s", name, err)
+		}
+
+		f.Set(v)
+	}
+
+	return s, nil
+}
+
+// Convert between numeric types, refusing to guess the unit of a duration,
+// or (unless lossy conversions are allowed) to lose a value's fraction or
+// wrap it around
+func convertNumber(value reflect.Value, to reflect.Type, lossy bool) (reflect.Value, error) {
+
+	v := reflect.New(to).Elem()
+
+	if to == durationType {
+		return value, fmt.Errorf("Can't convert %v to %s without a unit", value.Interface(), to)
+	}
+
+	if lossy {
+		return value.Convert(to), nil
+	}
+
+	overflow := false
+
+	switch {
+	case isSigned(value.Type()):
+		i := value.Int()
+
+		switch {
+		case isSigned(to):
+			overflow = v.OverflowInt(i)
+			v.SetInt(i)
+		case isUnsigned(to):
+			if i < 0 {
+				return value, fmt.Errorf("Can't convert negative %v to %s", value.Interface(), to)
+			}
+
+			overflow = v.OverflowUint(uint64(i))
+			v.SetUint(uint64(i))
+		default:
+			v.SetFloat(float64(i))
+		}
+	case isUnsigned(value.Type()):
+		u := value.Uint()
+
+		switch {
+		case isSigned(to):
+			overflow = u > math.MaxInt64 || v.OverflowInt(int64(u))
+			v.SetInt(int64(u))
+		case isUnsigned(to):
+			overflow = v.OverflowUint(u)
+			v.SetUint(u)
+		default:
+			v.SetFloat(float64(u))
+		}
+	default:
+		f := value.Float()
+
+		if (isSigned(to) || isUnsigned(to)) && f != math.Trunc(f) {
+			return value, fmt.Errorf("Can't convert %v to %s without losing its fraction", value.Interface(), to)
+		}
+
+		switch {
+		case isSigned(to):
+			overflow = f < math.MinInt64 || f >= math.MaxInt64 || v.OverflowInt(int64(f))
+			v.SetInt(int64(f))
+		case isUnsigned(to):
+			if f < 0 {
+				return value, fmt.Errorf("Can't convert negative %v to %s", value.Interface(), to)
+			}
+
+			overflow = f >= math.MaxUint64 || v.OverflowUint(uint64(f))
+			v.SetUint(uint64(f))
+		default:
+			overflow = v.OverflowFloat(f)
+			v.SetFloat(f)
+		}
+	}
+
+	if overflow {
+		return value, fmt.Errorf("%v overflows %s", value.Interface(), to)
+	}
+
+	return v, nil
+}
+
+// Returns true for slices that a string can be converted to directly
+func isByteOrRuneSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && (t.Elem().Kind() == reflect.Uint8 || t.Elem().Kind() == reflect.Int32)
+}
+
+func isSigned(t reflect.Type) bool {
+	return t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64
+}
+
+func isUnsigned(t reflect.Type) bool {
+	return t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr
+}
+
+func isNumeric(t reflect.Type) bool {
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// Convert a string (typically from a datasource that only deals in
+// strings, such as the environment) into a value of a basic type.
+func convertString(s string, t reflect.Type) (reflect.Value, error) {
+
+	v := reflect.New(t).Elem()
+
+	// Durations are int64s, but they're not expressed as integers
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+
+		if err != nil {
+			return v, err
+		}
+
+		v.SetInt(int64(d))
+
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+
+		if err != nil {
+			return v, err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, t.Bits())
+
+		if err != nil {
+			return v, err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 0, t.Bits())
+
+		if err != nil {
+			return v, err
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+
+		if err != nil {
+			return v, err
+		}
+
+		v.SetFloat(f)
+	default:
+		return v, fmt.Errorf("Can't convert a string to %s", t)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,323 @@
+package inj
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Strings should be parsed into basic types
+func Test_ConvertString(t *testing.T) {
+
+	for i, c := range []struct {
+		input    string
+		expected interface{}
+	}{
+		{"hello", "hello"},
+		{"true", true},
+		{"-8080", -8080},
+		{"0x10", int64(16)},
+		{"255", uint8(255)},
+		{"1.5", 1.5},
+		{"30s", 30 * time.Second},
+	} {
+		v, err := convertString(c.input, reflect.TypeOf(c.expected))
+
+		if err != nil {
+			t.Errorf("[%d] convertString: %s", i, err)
+			continue
+		}
+
+		if g, e := v.Interface(), c.expected; g != e {
+			t.Errorf("[%d] Expected %v, got %v", i, e, g)
+		}
+	}
+}
+
+// Invalid strings and unsupported types should error
+func Test_ConvertStringSadPath(t *testing.T) {
+
+	for i, c := range []struct {
+		input string
+		typ   reflect.Type
+	}{
+		{"yes please", reflect.TypeOf(true)},
+		{"256", reflect.TypeOf(uint8(0))},
+		{"thirty seconds", reflect.TypeOf(time.Second)},
+		{"x", reflect.TypeOf(struct{}{})},
+	} {
+		if _, err := convertString(c.input, c.typ); err == nil {
+			t.Errorf("[%d] convertString didn't error", i)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////
+// Types for graph conversion tests
+///////////////////////////////////////////////////////////////
+
+type convertLevel int
+
+type convertNested struct {
+	Name  string
+	Ports []int
+}
+
+type convertDep struct {
+	Int      int               `inj:"int"`
+	Uint     uint16            `inj:"int"`
+	Float    float32           `inj:"float"`
+	String   string            `inj:"int"`
+	Bool     bool              `inj:"bool"`
+	Duration time.Duration     `inj:"duration"`
+	Time     time.Time         `inj:"time"`
+	URL      *url.URL          `inj:"url"`
+	IP       net.IP            `inj:"ip"`
+	Strings  []string          `inj:"list"`
+	Split    []int             `inj:"csv"`
+	Map      map[string]int    `inj:"map"`
+	Nested   convertNested     `inj:"nested"`
+	Ptr      *convertNested    `inj:"nested"`
+	Level    convertLevel      `inj:"level"`
+	Unset    map[string]string `inj:"missing"`
+}
+
+// Datasource values should be converted to the types of the fields
+func Test_GraphConversion(t *testing.T) {
+
+	g, dep := newGraph(), convertDep{}
+
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"int":      "8080",
+		"float":    1.5,
+		"bool":     "true",
+		"duration": "30s",
+		"time":     "2015-08-01T12:00:00Z",
+		"url":      "https://example.com/path",
+		"ip":       "10.0.0.1",
+		"list":     []interface{}{"one", "two"},
+		"csv":      "1, 2,3",
+		"map":      map[string]interface{}{"a": 1.0, "b": "2"},
+		"nested":   map[string]interface{}{"name": "x", "ports": []interface{}{80.0, "443"}},
+		"level":    "debug",
+	}))
+
+	if e := g.AddConverter(func(s string) (convertLevel, error) {
+		if s == "debug" {
+			return 1, nil
+		}
+
+		return 0, errors.New("unknown level")
+	}); e != nil {
+		t.Fatalf("AddConverter: %s", e)
+	}
+
+	g.Provide(&dep, map[string]string{})
+
+	assertNoGraphErrors(t, g)
+
+	expected := convertDep{
+		Int:      8080,
+		Uint:     8080,
+		Float:    1.5,
+		String:   "8080",
+		Bool:     true,
+		Duration: 30 * time.Second,
+		Time:     time.Date(2015, 8, 1, 12, 0, 0, 0, time.UTC),
+		URL:      &url.URL{Scheme: "https", Host: "example.com", Path: "/path"},
+		IP:       net.ParseIP("10.0.0.1"),
+		Strings:  []string{"one", "two"},
+		Split:    []int{1, 2, 3},
+		Map:      map[string]int{"a": 1, "b": 2},
+		Nested:   convertNested{"x", []int{80, 443}},
+		Ptr:      &convertNested{"x", []int{80, 443}},
+		Level:    1,
+		Unset:    map[string]string{},
+	}
+
+	if !reflect.DeepEqual(dep, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, dep)
+	}
+}
+
+// Numbers should be formatted rather than converted to runes
+func Test_GraphConversionNumberToString(t *testing.T) {
+
+	v, err := newGraph().convert(reflect.ValueOf(65), reflect.TypeOf(""))
+
+	if err != nil || v.String() != "65" {
+		t.Errorf("Expected '65', got '%v' (%v)", v, err)
+	}
+}
+
+// Numbers should be converted between types without losing anything
+func Test_GraphConversionNumbers(t *testing.T) {
+
+	g := newGraph()
+
+	for i, c := range []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{8080.0, 8080},
+		{-5, int8(-5)},
+		{uint64(255), uint8(255)},
+		{int64(math.MaxInt64), int64(math.MaxInt64)},
+		{3, 3.0},
+		{1.5, float32(1.5)},
+	} {
+		v, err := g.convert(reflect.ValueOf(c.value), reflect.TypeOf(c.expected))
+
+		if err != nil || v.Interface() != c.expected {
+			t.Errorf("[%d] Expected %v, got %v (%v)", i, c.expected, v, err)
+		}
+	}
+}
+
+// Lossy numeric conversions should only happen when they're allowed
+func Test_GraphConversionLossy(t *testing.T) {
+
+	g := newGraph()
+
+	for i, c := range []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{16.01, 16},
+		{300, int8(44)},
+		{-1, uint8(255)},
+	} {
+		if _, err := g.convert(reflect.ValueOf(c.value), reflect.TypeOf(c.expected)); err == nil {
+			t.Errorf("[%d] Expected an error converting %v", i, c.value)
+		}
+	}
+
+	g.SetLossyConversion(true)
+
+	for i, c := range []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{16.01, 16},
+		{300, int8(44)},
+		{-1, uint8(255)},
+	} {
+		v, err := g.convert(reflect.ValueOf(c.value), reflect.TypeOf(c.expected))
+
+		if err != nil || v.Interface() != c.expected {
+			t.Errorf("[%d] Expected %v, got %v (%v)", i, c.expected, v, err)
+		}
+	}
+
+	if _, err := g.convert(reflect.ValueOf(30), durationType); err == nil {
+		t.Errorf("Expected an error converting a number to a duration")
+	}
+}
+
+// Strings should convert to and from byte and rune slices
+func Test_GraphConversionBytesAndRunes(t *testing.T) {
+
+	type config struct {
+		Bytes []byte `inj:"bytes"`
+		Runes []rune `inj:"runes"`
+		Text  string `inj:"text"`
+	}
+
+	c := &config{}
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"bytes": "héllo",
+		"runes": "héllo",
+		"text":  []byte("héllo"),
+	}))
+	g.Provide(c)
+
+	assertNoGraphErrors(t, g)
+
+	if string(c.Bytes) != "héllo" || string(c.Runes) != "héllo" || c.Text != "héllo" {
+		t.Errorf("Unexpected values %q, %q and %q", c.Bytes, c.Runes, c.Text)
+	}
+}
+
+// Values that are found but can't be converted should be reported as such
+func Test_GraphConversionErrors(t *testing.T) {
+
+	type config struct {
+		Port     int           `inj:"port"`
+		Timeout  time.Duration `inj:"timeout"`
+		Password int           `inj:"password,secret"`
+	}
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"port":     "abc",
+		"timeout":  "30",
+		"password": "hunter2",
+	}))
+	g.Provide(&config{}, 80)
+
+	valid, errs := g.Assert()
+
+	if valid || len(errs) != 3 {
+		t.Fatalf("Expected 3 errors, got %v", errs)
+	}
+
+	sort.Strings(errs)
+
+	for i, expected := range []string{
+		`.Password: Couldn't convert password from datasource *inj.MockDatasourceReader: Can't convert a secret value to int`,
+		`.Port: Couldn't convert port from datasource *inj.MockDatasourceReader: strconv.ParseInt: parsing "abc": invalid syntax`,
+		`.Timeout: Couldn't convert timeout from datasource *inj.MockDatasourceReader: time: missing unit in duration "30"`,
+	} {
+		if !strings.HasSuffix(errs[i], expected) {
+			t.Errorf("Expected an error ending %q, got %q", expected, errs[i])
+		}
+	}
+}
+
+// Invalid conversions and converters should error
+func Test_GraphConversionSadPath(t *testing.T) {
+
+	g := newGraph()
+
+	for i, c := range []struct {
+		value interface{}
+		typ   reflect.Type
+	}{
+		{"eighty", reflect.TypeOf(0)},
+		{[]interface{}{"x"}, reflect.TypeOf([]int{})},
+		{map[string]interface{}{"ports": "x"}, reflect.TypeOf(convertNested{})},
+		{"not a time", reflect.TypeOf(time.Time{})},
+		{"999.0.0.1", reflect.TypeOf(net.IP{})},
+		{struct{}{}, reflect.TypeOf(0)},
+		{nil, reflect.TypeOf(0)},
+		{8080.9, reflect.TypeOf(0)},
+		{300, reflect.TypeOf(int8(0))},
+		{-1, reflect.TypeOf(uint(0))},
+		{-1.0, reflect.TypeOf(uint(0))},
+		{uint64(math.MaxUint64), reflect.TypeOf(int64(0))},
+		{1e20, reflect.TypeOf(int64(0))},
+		{1e300, reflect.TypeOf(float32(0))},
+		{5, reflect.TypeOf(time.Second)},
+	} {
+		if _, err := g.convert(reflect.ValueOf(c.value), c.typ); err == nil {
+			t.Errorf("[%d] convert didn't error", i)
+		}
+	}
+
+	for i, fn := range []interface{}{
+		"not a function",
+		func() int { return 0 },
+		func(s string) (int, int) { return 0, 0 },
+	} {
+		if e := g.AddConverter(fn); e == nil {
+			t.Errorf("[%d] AddConverter didn't error", i)
+		}
+	}
+}
@@ -16,17 +16,42 @@ package inj
 // When trying to connect the dependency on an instance of the struct above, inj will
 // poll any available DatasourceReaders in the graph by calling their Read() function
 // with the string argument "some.path.in.a.datasource". If the function doesn't return
-// an error, then the resultant value will be used for the dependency injection.
+// an error, then the resultant value will be used for the dependency injection. If it
+// returns ErrNotFound, the next DatasourceReader is polled; any other error is reported
+// as a failure of the datasource.
 //
 // A struct tag may contain multiple datasource paths, separated by commas. The paths
 // will be polled in order of their appearance in the code, and the value from the first
 // DatasourceReader that doesn't return an error on its Read() function will be used to
 // meet the dependency.
 //
+// A struct field can be populated in its entirety using the prefix flag. Each of its
+// fields is read from the prefix followed by the field's lower case name (or the value
+// of its injkey tag), recursively:
+//
+//  type DBConfig struct {
+//      Host     string                    // read from db.host
+//      MaxConns int `injkey:"max_conns"`  // read from db.max_conns
+//      TLS      struct{ Cert string }     // read from db.tls.cert
+//  }
+//
+//  type MyStruct stuct {
+//      DB DBConfig `inj:"db,prefix"`
+//  }
+//
+// Fields inside a prefixed struct are only ever read from datasources, and if no
+// DatasourceReader has a value for them, they're left as they are.
+//
 // DatasourceWriters function in a similar way: when a value is set on an instance of a
 // struct via inj's dependency injection, any associated DatasourceWriters' Write() functions
 // are called for each datasource path.
 //
+// A WatchableDatasource can signal that some of its values have changed, in which case
+// only the fields bound to those paths are re-assigned. Nodes that implement Reloader
+// have their OnReload() functions called with the paths that affected them. Fields are
+// re-assigned on a background goroutine, so see WatchableDatasource before reading them
+// from other goroutines.
+//
 type Datasource interface {
 	DatasourceReader
 	DatasourceWriter
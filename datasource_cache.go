@@ -0,0 +1,234 @@
+package inj
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	value   interface{}
+	found   bool
+	expires time.Time
+}
+
+// A CachedDatasource wraps a DatasourceReader, remembering the values it
+// reads (and the keys it doesn't have) for a fixed time, so that repeated
+// calls to Provide() don't repeatedly query a remote source. Failures other
+// than missing keys aren't cached.
+//
+//  ds := inj.NewCachedDatasource(remote, time.Minute)
+//
+// If the wrapped reader implements BatchDatasourceReader, uncached keys are
+// fetched in a single call. If it implements WatchableDatasource, changed keys
+// are invalidated before the change is passed on to the graph.
+type CachedDatasource struct {
+	mu       sync.Mutex
+	reader   DatasourceReader
+	ttl      time.Duration
+	entries  map[string]cacheEntry
+	watchers watchers
+	once     sync.Once
+}
+
+// Wrap a DatasourceReader in a cache. Entries expire after the TTL, or
+// never if the TTL is zero.
+func NewCachedDatasource(r DatasourceReader, ttl time.Duration) *CachedDatasource {
+
+	return &CachedDatasource{
+		reader:  r,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Implementation of the DatasourceReader interface
+func (c *CachedDatasource) Read(key string) (interface{}, error) {
+	return c.ReadContext(context.Background(), key)
+}
+
+// Implementation of the ContextDatasourceReader interface. The context is
+// passed on to the wrapped reader when the key isn't cached.
+func (c *CachedDatasource) ReadContext(ctx context.Context, key string) (interface{}, error) {
+
+	if e, ok := c.lookup(key); ok {
+		return e.value, e.err(key)
+	}
+
+	v, err := readContext(ctx, c.reader, key)
+
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	c.store(key, v, err == nil)
+
+	return v, err
+}
+
+// Implementation of the BatchDatasourceReader interface
+func (c *CachedDatasource) ReadMany(keys []string) (map[string]interface{}, error) {
+	return c.ReadManyContext(context.Background(), keys)
+}
+
+// Implementation of the ContextBatchDatasourceReader interface. The context
+// is passed on to the wrapped reader for the keys that aren't cached.
+func (c *CachedDatasource) ReadManyContext(ctx context.Context, keys []string) (map[string]interface{}, error) {
+
+	values := make(map[string]interface{})
+	missing := make([]string, 0)
+
+	for _, key := range keys {
+		if e, ok := c.lookup(key); !ok {
+			missing = append(missing, key)
+		} else if e.found {
+			values[key] = e.value
+		}
+	}
+
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	// Without batch support, each key is read in turn
+	b, ok := c.reader.(BatchDatasourceReader)
+
+	if !ok {
+
+		for _, key := range missing {
+
+			v, err := c.ReadContext(ctx, key)
+
+			if err == nil {
+				values[key] = v
+			} else if !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	}
+
+	fetched, err := readManyContext(ctx, b, missing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range missing {
+
+		v, found := fetched[key]
+		c.store(key, v, found)
+
+		if found {
+			values[key] = v
+		}
+	}
+
+	return values, nil
+}
+
+// Implementation of the SourceKeyer interface
+func (c *CachedDatasource) SourceKey(path string) string {
+	return sourceKey(c.reader, path)
+}
+
+// Implementation of the WatchableDatasource interface. If the wrapped reader
+// isn't watchable, the channel is closed immediately.
+func (c *CachedDatasource) Watch() <-chan []string {
+
+	c.once.Do(func() {
+
+		w, ok := c.reader.(WatchableDatasource)
+
+		if !ok {
+			c.watchers.close()
+			return
+		}
+
+		changes := w.Watch()
+
+		go func() {
+			for paths := range changes {
+				c.Invalidate(paths...)
+				c.watchers.notify(paths)
+			}
+
+			c.watchers.close()
+		}()
+	})
+
+	return c.watchers.add()
+}
+
+// The provenance of a path, if the wrapped reader records it (as a
+// LayeredDatasource does)
+func (c *CachedDatasource) Provenance(path string) (Provenance, bool) {
+
+	if pr, ok := c.reader.(provenanceReader); ok {
+		return pr.Provenance(path)
+	}
+
+	return Provenance{}, false
+}
+
+// Pass secret paths on to the wrapped reader
+func (c *CachedDatasource) markSecret(path string) {
+	markSecret(c.reader, path)
+}
+
+// Remove keys from the cache, so that they're read again from the wrapped
+// reader. With no keys, the whole cache is cleared.
+func (c *CachedDatasource) Invalidate(keys ...string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(keys) == 0 {
+		c.entries = make(map[string]cacheEntry)
+		return
+	}
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+// Find an unexpired entry
+func (c *CachedDatasource) lookup(key string) (cacheEntry, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, exists := c.entries[key]
+
+	if !exists {
+		return e, false
+	}
+
+	if c.ttl > 0 && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return e, false
+	}
+
+	return e, true
+}
+
+func (c *CachedDatasource) store(key string, value interface{}, found bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry{value, found, time.Now().Add(c.ttl)}
+}
+
+// The error for a cached entry
+func (e cacheEntry) err(key string) error {
+
+	if e.found {
+		return nil
+	}
+
+	return newNotFoundError("Key %s not found", key)
+}
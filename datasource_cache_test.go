@@ -0,0 +1,266 @@
+package inj
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A reader that counts its calls
+type countingReader struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	reads  int
+	fail   error
+}
+
+func (c *countingReader) Read(key string) (interface{}, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reads++
+
+	if c.fail != nil {
+		return nil, c.fail
+	}
+
+	if v, exists := c.values[key]; exists {
+		return v, nil
+	}
+
+	return nil, newNotFoundError("Key %s not found", key)
+}
+
+func (c *countingReader) count() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reads
+}
+
+// A counting reader that also supports batches
+type countingBatchReader struct {
+	countingReader
+	batches [][]string
+}
+
+func (c *countingBatchReader) ReadMany(keys []string) (map[string]interface{}, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.batches = append(c.batches, keys)
+
+	if c.fail != nil {
+		return nil, c.fail
+	}
+
+	values := make(map[string]interface{})
+
+	for _, key := range keys {
+		if v, exists := c.values[key]; exists {
+			values[key] = v
+		}
+	}
+
+	return values, nil
+}
+
+type cacheTester struct {
+	Port    int    `inj:"server.port"`
+	Host    string `inj:"server.host,hostname"`
+	Missing string `inj:"missing"`
+}
+
+// Values and missing keys should be cached until they expire or
+// are invalidated
+func Test_CachedDatasource(t *testing.T) {
+
+	r := &countingReader{values: map[string]interface{}{"a": 1}}
+	c := NewCachedDatasource(r, 0)
+
+	for i := 0; i < 3; i++ {
+		if v, err := c.Read("a"); err != nil || v != 1 {
+			t.Errorf("Read(a): unexpected %v (%v)", v, err)
+		}
+
+		if _, err := c.Read("b"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Read(b): expected ErrNotFound, got %v", err)
+		}
+	}
+
+	if g, e := r.count(), 2; g != e {
+		t.Errorf("Expected %d reads, got %d", e, g)
+	}
+
+	c.Invalidate("a")
+	c.Read("a")
+	c.Read("b")
+
+	if g, e := r.count(), 3; g != e {
+		t.Errorf("Expected %d reads after invalidating a key, got %d", e, g)
+	}
+
+	c.Invalidate()
+	c.Read("a")
+	c.Read("b")
+
+	if g, e := r.count(), 5; g != e {
+		t.Errorf("Expected %d reads after invalidating everything, got %d", e, g)
+	}
+
+	// Failures aren't cached
+	r.fail = errors.New("Connection refused")
+	c.Invalidate()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Read("a"); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected a failure, got %v", err)
+		}
+	}
+
+	if g, e := r.count(), 7; g != e {
+		t.Errorf("Expected %d reads after failures, got %d", e, g)
+	}
+}
+
+// Entries should expire after the TTL
+func Test_CachedDatasourceTTL(t *testing.T) {
+
+	r := &countingReader{values: map[string]interface{}{"a": 1}}
+	c := NewCachedDatasource(r, 10*time.Millisecond)
+
+	c.Read("a")
+	c.Read("a")
+
+	if g, e := r.count(), 1; g != e {
+		t.Errorf("Expected %d reads, got %d", e, g)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	c.Read("a")
+
+	if g, e := r.count(), 2; g != e {
+		t.Errorf("Expected %d reads after expiry, got %d", e, g)
+	}
+}
+
+// A cache in a graph should stop repeated Provide() calls from reaching
+// the wrapped reader
+func Test_CachedDatasourceInGraph(t *testing.T) {
+
+	r := &countingReader{values: map[string]interface{}{"server.port": 80, "hostname": "localhost"}}
+
+	g := newGraph()
+	g.AddDatasource(NewCachedDatasource(r, 0))
+
+	for i := 0; i < 5; i++ {
+		g.Provide(&cacheTester{}, i)
+	}
+
+	// server.port, server.host, hostname and missing
+	if g, e := r.count(), 4; g != e {
+		t.Errorf("Expected %d reads, got %d", e, g)
+	}
+}
+
+// Batch readers should be read once per connect
+func Test_BatchDatasourceReader(t *testing.T) {
+
+	r := &countingBatchReader{countingReader: countingReader{
+		values: map[string]interface{}{"server.port": 80, "hostname": "localhost"},
+	}}
+
+	c := &cacheTester{}
+
+	g := newGraph()
+	g.AddDatasource(r)
+	g.Provide(c)
+
+	if c.Port != 80 || c.Host != "localhost" {
+		t.Errorf("Unexpected values %+v", c)
+	}
+
+	if g, e := r.count(), 0; g != e {
+		t.Errorf("Expected %d reads, got %d", e, g)
+	}
+
+	// AddDatasource() connects an empty graph, so there's only one batch
+	if g, e := r.batches, [][]string{{"hostname", "missing", "server.host", "server.port"}}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected batches %v, got %v", e, g)
+	}
+
+	// A failed batch falls back to reading paths individually
+	r.fail = errors.New("Connection refused")
+
+	if err := g.Provide(c); err == nil {
+		t.Errorf("Expected a datasource failure")
+	}
+
+	if r.count() == 0 {
+		t.Errorf("Expected individual reads after a failed batch")
+	}
+}
+
+// A cache around a batch reader should only fetch uncached keys
+func Test_CachedBatchDatasource(t *testing.T) {
+
+	r := &countingBatchReader{countingReader: countingReader{
+		values: map[string]interface{}{"a": 1, "b": 2},
+	}}
+
+	c := NewCachedDatasource(r, 0)
+	c.Read("a")
+
+	values, err := c.ReadMany([]string{"a", "b", "c"})
+
+	if err != nil {
+		t.Fatalf("ReadMany: %s", err)
+	}
+
+	if e := map[string]interface{}{"a": 1, "b": 2}; !reflect.DeepEqual(values, e) {
+		t.Errorf("Expected %v, got %v", e, values)
+	}
+
+	c.ReadMany([]string{"a", "b", "c"})
+
+	if g, e := r.batches, [][]string{{"b", "c"}}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected batches %v, got %v", e, g)
+	}
+}
+
+// Changes to a watched reader should invalidate the cache
+func Test_CachedDatasourceWatch(t *testing.T) {
+
+	m := NewMemoryDatasource(map[string]interface{}{"server.port": 80})
+	defer m.Close()
+
+	r := &reloadTester{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(NewCachedDatasource(m, 0))
+	g.Provide(r)
+
+	m.Set(map[string]interface{}{"server.port": 8080})
+
+	paths := waitForReload(t, r)
+	sort.Strings(paths)
+
+	if e := []string{"server.port"}; !reflect.DeepEqual(paths, e) {
+		t.Errorf("Expected reload of %v, got %v", e, paths)
+	}
+
+	if r.Port != 8080 {
+		t.Errorf("Expected port 8080, got %d", r.Port)
+	}
+
+	// Unwatchable readers close the channel straight away
+	if _, open := <-NewCachedDatasource(&countingReader{}, 0).Watch(); open {
+		t.Errorf("Expected a closed channel")
+	}
+}
@@ -0,0 +1,96 @@
+package inj
+
+import "context"
+
+// A ContextDatasourceReader is a DatasourceReader that can be cancelled, such
+// as a client for a remote configuration store. When a graph reads from it,
+// ReadContext() is called instead of Read(), with a context that's done when
+// the read times out (see SetReadTimeout()) or the context passed to
+// ProvideContext() is cancelled.
+type ContextDatasourceReader interface {
+	DatasourceReader
+	ReadContext(ctx context.Context, key string) (interface{}, error)
+}
+
+// A ContextBatchDatasourceReader is a BatchDatasourceReader that can be
+// cancelled. When a graph prefetches its datasource paths, ReadManyContext()
+// is called instead of ReadMany(), with the same context that ReadContext()
+// would receive.
+type ContextBatchDatasourceReader interface {
+	BatchDatasourceReader
+	ReadManyContext(ctx context.Context, keys []string) (map[string]interface{}, error)
+}
+
+// Read a key from a DatasourceReader, giving up when the context is done.
+// Readers that don't accept a context are abandoned rather than cancelled.
+func readContext(ctx context.Context, r DatasourceReader, key string) (interface{}, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if cr, ok := r.(ContextDatasourceReader); ok {
+		return cr.ReadContext(ctx, key)
+	}
+
+	// The context can't be done, so there's nothing to wait for
+	if ctx.Done() == nil {
+		return r.Read(key)
+	}
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+
+	c := make(chan result, 1)
+
+	go func() {
+		v, err := r.Read(key)
+		c <- result{v, err}
+	}()
+
+	select {
+	case res := <-c:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Read many keys from a BatchDatasourceReader, giving up when the context is
+// done. As with readContext(), readers that don't accept a context are
+// abandoned rather than cancelled.
+func readManyContext(ctx context.Context, r BatchDatasourceReader, keys []string) (map[string]interface{}, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if cr, ok := r.(ContextBatchDatasourceReader); ok {
+		return cr.ReadManyContext(ctx, keys)
+	}
+
+	if ctx.Done() == nil {
+		return r.ReadMany(keys)
+	}
+
+	type result struct {
+		values map[string]interface{}
+		err    error
+	}
+
+	c := make(chan result, 1)
+
+	go func() {
+		v, err := r.ReadMany(keys)
+		c <- result{v, err}
+	}()
+
+	select {
+	case res := <-c:
+		return res.values, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
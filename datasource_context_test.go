@@ -0,0 +1,149 @@
+package inj
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// A reader that blocks until its context is done
+type blockingContextReader struct {
+	contexts int
+}
+
+func (b *blockingContextReader) Read(key string) (interface{}, error) {
+	return nil, errors.New("Read() shouldn't be called")
+}
+
+func (b *blockingContextReader) ReadContext(ctx context.Context, key string) (interface{}, error) {
+
+	b.contexts++
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+// A reader that blocks until it's released, and can't be cancelled
+type blockingReader struct {
+	release chan struct{}
+}
+
+func (b *blockingReader) Read(key string) (interface{}, error) {
+
+	<-b.release
+
+	return nil, newNotFoundError("Key %s not found", key)
+}
+
+type contextTester struct {
+	Value string `inj:"some.value"`
+}
+
+// Reads should be cancelled when they time out
+func Test_ReadTimeout(t *testing.T) {
+
+	for name, d := range map[string]DatasourceReader{
+		"context": &blockingContextReader{},
+		"plain":   &blockingReader{make(chan struct{})},
+		"layered": NewLayeredDatasource().Add("remote", PriorityFile, &blockingContextReader{}),
+		"cached":  NewCachedDatasource(&blockingContextReader{}, time.Minute),
+	} {
+		g := newGraph()
+		g.SetReadTimeout(10 * time.Millisecond)
+		g.AddDatasource(d)
+
+		err := g.Provide(&contextTester{})
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected a deadline error, got %v", name, err)
+		}
+
+		var dserr *DatasourceError
+
+		if !errors.As(err, &dserr) || dserr.Path != "some.value" {
+			t.Errorf("%s: expected a *DatasourceError for some.value, got %v", name, err)
+		}
+
+		if valid, _ := g.Assert(); valid {
+			t.Errorf("%s: expected Assert() to fail", name)
+		}
+
+		if b, ok := d.(*blockingReader); ok {
+			close(b.release)
+		}
+	}
+}
+
+// Batch reads (including a cache's reads of uncached keys) should time out too
+func Test_ReadTimeoutCached(t *testing.T) {
+
+	b := &blockingReader{make(chan struct{})}
+	defer close(b.release)
+
+	g := newGraph()
+	g.SetReadTimeout(10 * time.Millisecond)
+	g.AddDatasource(NewCachedDatasource(b, time.Minute))
+
+	start := time.Now()
+	err := g.Provide(&contextTester{})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Provide() took %s", d)
+	}
+}
+
+// Cancelling the context passed to ProvideContext() should stop wiring
+func Test_ProvideContextCancelled(t *testing.T) {
+
+	r := &blockingContextReader{}
+
+	g := newGraph()
+	g.AddDatasource(r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := g.ProvideContext(ctx, &contextTester{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error, got %v", err)
+	}
+
+	if r.contexts != 0 {
+		t.Errorf("Expected no reads after cancellation, got %d", r.contexts)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := g.ProvideContext(ctx, &contextTester{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+
+	if r.contexts != 1 {
+		t.Errorf("Expected one read, got %d", r.contexts)
+	}
+}
+
+// Ordinary readers should be unaffected by contexts
+func Test_ProvideContextWithReader(t *testing.T) {
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"some.value": "hello"}))
+	g.SetReadTimeout(time.Second)
+
+	c := &contextTester{}
+
+	if err := g.ProvideContext(context.Background(), c); err != nil {
+		t.Fatalf("ProvideContext: %s", err)
+	}
+
+	assertNoGraphErrors(t, g)
+
+	if c.Value != "hello" {
+		t.Errorf("Expected hello, got %s", c.Value)
+	}
+}
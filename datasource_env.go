@@ -0,0 +1,127 @@
+package inj
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// An EnvOption configures the behaviour of an environment datasource
+type EnvOption func(*envDatasource)
+
+type envDatasource struct {
+	prefix     string
+	separator  string
+	upper      bool
+	fileSuffix string
+	mapper     func(string) string
+}
+
+// Create a DatasourceReader that reads values from environment variables. By
+// default, a datasource path is mapped to a variable name by replacing dots
+// (and dashes) with underscores, upper-casing the result and adding the prefix
+// (if there is one), separated by an underscore. With a prefix of "APP", the
+// path "db.host" is read from APP_DB_HOST.
+//
+// If the variable isn't set, but the same variable with a _FILE suffix is
+// (eg. APP_DB_PASSWORD_FILE), then the value is read from the file it names,
+// with any trailing newline removed. This is a common convention for passing
+// secrets to containers.
+//
+// Environment variables are always strings, which are converted to the type of
+// the dependent field when the value is assigned.
+func EnvDatasource(prefix string, opts ...EnvOption) DatasourceReader {
+
+	d := &envDatasource{
+		prefix:     prefix,
+		separator:  "_",
+		upper:      true,
+		fileSuffix: "_FILE",
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Replace dots in datasource paths with a separator other than an underscore.
+// The separator is also used between the prefix and the rest of the name.
+func EnvSeparator(sep string) EnvOption {
+	return func(d *envDatasource) {
+		d.separator = sep
+	}
+}
+
+// Don't upper-case variable names.
+func EnvPreserveCase() EnvOption {
+	return func(d *envDatasource) {
+		d.upper = false
+	}
+}
+
+// Change the suffix used for file indirection. An empty suffix disables it.
+func EnvFileSuffix(suffix string) EnvOption {
+	return func(d *envDatasource) {
+		d.fileSuffix = suffix
+	}
+}
+
+// Replace the default mapping from datasource paths to variable names. The
+// prefix is still added to the result of the mapper function.
+func EnvMapper(fn func(path string) string) EnvOption {
+	return func(d *envDatasource) {
+		d.mapper = fn
+	}
+}
+
+// Get the name of the environment variable for a datasource path. This is
+// an implementation of the SourceKeyer interface.
+func (d *envDatasource) SourceKey(path string) string {
+
+	var key string
+
+	if d.mapper != nil {
+		key = d.mapper(path)
+	} else {
+		key = strings.NewReplacer(".", d.separator, "-", d.separator).Replace(path)
+
+		if d.upper {
+			key = strings.ToUpper(key)
+		}
+	}
+
+	if d.prefix != "" && !strings.HasSuffix(d.prefix, d.separator) {
+		return d.prefix + d.separator + key
+	}
+
+	return d.prefix + key
+}
+
+// Implementation of the DatasourceReader interface
+func (d *envDatasource) Read(path string) (interface{}, error) {
+
+	key := d.SourceKey(path)
+
+	if value, exists := os.LookupEnv(key); exists {
+		return value, nil
+	}
+
+	if d.fileSuffix != "" {
+
+		if file, exists := os.LookupEnv(key + d.fileSuffix); exists {
+
+			b, err := ioutil.ReadFile(file)
+
+			if err != nil {
+				return nil, fmt.Errorf("Can't read %s%s: %s", key, d.fileSuffix, err)
+			}
+
+			return strings.TrimRight(string(b), "\r\n"), nil
+		}
+	}
+
+	return nil, newNotFoundError("Environment variable %s isn't set", key)
+}
@@ -0,0 +1,96 @@
+package inj
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////
+// A type with environment-sourced dependencies
+///////////////////////////////////////////////////////////////
+
+type envDep struct {
+	Host     string        `inj:"db.host"`
+	Port     int           `inj:"db.port"`
+	Debug    bool          `inj:"debug"`
+	Timeout  time.Duration `inj:"db.timeout"`
+	Password string        `inj:"db.password"`
+}
+
+///////////////////////////////////////////////////////////////
+// Unit tests
+///////////////////////////////////////////////////////////////
+
+// Paths should map to variable names
+func Test_EnvDatasourceKeys(t *testing.T) {
+
+	for i, c := range []struct {
+		ds       DatasourceReader
+		path     string
+		expected string
+	}{
+		{EnvDatasource(""), "db.host", "DB_HOST"},
+		{EnvDatasource("APP"), "db.host", "APP_DB_HOST"},
+		{EnvDatasource("APP_"), "db.read-only", "APP_DB_READ_ONLY"},
+		{EnvDatasource("app", EnvPreserveCase()), "db.host", "app_db_host"},
+		{EnvDatasource("APP", EnvSeparator("__")), "db.host", "APP__DB__HOST"},
+		{EnvDatasource("APP", EnvMapper(strings.ToLower)), "DB.HOST", "APP_db.host"},
+	} {
+		if g, e := c.ds.(*envDatasource).SourceKey(c.path), c.expected; g != e {
+			t.Errorf("[%d] Expected %s, got %s", i, e, g)
+		}
+	}
+}
+
+// Values should be read from the environment and converted
+func Test_EnvDatasourceInGraph(t *testing.T) {
+
+	secret := filepath.Join(t.TempDir(), "password")
+
+	if err := ioutil.WriteFile(secret, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	t.Setenv("INJTEST_DB_HOST", "localhost")
+	t.Setenv("INJTEST_DB_PORT", "5432")
+	t.Setenv("INJTEST_DEBUG", "true")
+	t.Setenv("INJTEST_DB_TIMEOUT", "5s")
+	t.Setenv("INJTEST_DB_PASSWORD_FILE", secret)
+
+	dep := envDep{}
+	g := newGraph()
+
+	g.AddDatasource(EnvDatasource("INJTEST"))
+	g.Provide(&dep)
+
+	assertNoGraphErrors(t, g)
+
+	expected := envDep{"localhost", 5432, true, 5 * time.Second, "hunter2"}
+
+	if dep != expected {
+		t.Errorf("Expected %+v, got %+v", expected, dep)
+	}
+}
+
+// Missing variables and files should error
+func Test_EnvDatasourceSadPath(t *testing.T) {
+
+	t.Setenv("INJTEST_MISSING_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	ds := EnvDatasource("INJTEST")
+
+	if _, err := ds.Read("not.set"); err == nil {
+		t.Errorf("Read didn't error for an unset variable")
+	}
+
+	if _, err := ds.Read("missing"); err == nil {
+		t.Errorf("Read didn't error for a missing file")
+	}
+
+	if _, err := EnvDatasource("INJTEST", EnvFileSuffix("")).Read("missing"); err == nil {
+		t.Errorf("Read didn't error with file indirection disabled")
+	}
+}
@@ -0,0 +1,198 @@
+package inj
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// A ParseError is returned when a configuration file is malformed. It's
+// distinct from the error returned by Read() when a key is missing, which
+// only means that the next DatasourceReader in the graph should be tried.
+type ParseError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+// Implementation of the error interface
+func (e *ParseError) Error() string {
+
+	name := e.Filename
+
+	if name == "" {
+		name = "input"
+	}
+
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", name, e.Line, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", name, e.Message)
+}
+
+// A datasource backed by a tree of maps and slices, as decoded
+// from a configuration file
+type fileDatasource struct {
+	filename string
+	data     interface{}
+}
+
+// A function that decodes a configuration file into a tree
+type fileParser func(filename string, b []byte) (interface{}, error)
+
+// Implementation of the DatasourceReader interface. Paths are split on dots,
+// and each element is used as a key for a map or, if it's numeric, an index
+// into an array (eg. "servers.0.host").
+func (d *fileDatasource) Read(path string) (interface{}, error) {
+	return lookupPath(d.data, path)
+}
+
+// Implementation of the SourceKeyer interface
+func (d *fileDatasource) SourceKey(path string) string {
+
+	if d.filename == "" {
+		return path
+	}
+
+	return path + " in " + d.filename
+}
+
+// Find the value for a dotted path in a tree of maps and slices
+func lookupPath(data interface{}, path string) (interface{}, error) {
+
+	current := data
+
+	for _, key := range strings.Split(path, ".") {
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, exists := node[key]
+
+			if !exists {
+				return nil, newNotFoundError("Key %s not found (no %s)", path, key)
+			}
+
+			current = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, newNotFoundError("Key %s not found (no index %s)", path, key)
+			}
+
+			current = node[i]
+		default:
+			return nil, newNotFoundError("Key %s not found (can't look up %s in a scalar)", path, key)
+		}
+	}
+
+	// Null values can't be assigned to anything
+	if current == nil {
+		return nil, newNotFoundError("Key %s not found (value is null)", path)
+	}
+
+	return current, nil
+}
+
+func newFileDatasource(filename string, r io.Reader, parse fileParser) (DatasourceReader, error) {
+
+	b, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parse(filename, b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileDatasource{filename, data}, nil
+}
+
+func newFileDatasourceFromFile(filename string, parse fileParser) (DatasourceReader, error) {
+
+	b, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileDatasource(filename, bytes.NewReader(b), parse)
+}
+
+///////////////////////////////////////////////
+// JSON
+///////////////////////////////////////////////
+
+// Create a DatasourceReader from JSON data. Numbers are decoded as float64s,
+// and converted to the type of the dependent field when they're assigned. A
+// *ParseError is returned if the data is malformed.
+func JSONDatasource(r io.Reader) (DatasourceReader, error) {
+	return newFileDatasource("", r, parseJSON)
+}
+
+// Create a DatasourceReader from a JSON file. See JSONDatasource.
+func JSONFileDatasource(filename string) (DatasourceReader, error) {
+	return newFileDatasourceFromFile(filename, parseJSON)
+}
+
+func parseJSON(filename string, b []byte) (interface{}, error) {
+
+	var data interface{}
+
+	if err := json.Unmarshal(b, &data); err != nil {
+
+		perr := &ParseError{Filename: filename, Message: err.Error()}
+
+		if serr, ok := err.(*json.SyntaxError); ok {
+			perr.Line = 1 + bytes.Count(b[:serr.Offset], []byte("\n"))
+		}
+
+		return nil, perr
+	}
+
+	return data, nil
+}
+
+///////////////////////////////////////////////
+// YAML
+///////////////////////////////////////////////
+
+// Create a DatasourceReader from YAML data. Only a practical subset of YAML
+// is supported: block mappings and sequences, flow sequences and mappings on
+// a single line, plain and quoted scalars, block scalars (| and >) and
+// comments. Anchors, aliases, tags and multiple documents aren't supported. A
+// *ParseError is returned if the data is malformed.
+func YAMLDatasource(r io.Reader) (DatasourceReader, error) {
+	return newFileDatasource("", r, parseYAML)
+}
+
+// Create a DatasourceReader from a YAML file. See YAMLDatasource.
+func YAMLFileDatasource(filename string) (DatasourceReader, error) {
+	return newFileDatasourceFromFile(filename, parseYAML)
+}
+
+///////////////////////////////////////////////
+// TOML
+///////////////////////////////////////////////
+
+// Create a DatasourceReader from TOML data. Most of TOML is supported,
+// including tables, arrays of tables, inline tables, dotted keys and all
+// string forms. Offset date-times are decoded as time.Times; local dates
+// and times are left as strings. A *ParseError is returned if the data is
+// malformed.
+func TOMLDatasource(r io.Reader) (DatasourceReader, error) {
+	return newFileDatasource("", r, parseTOML)
+}
+
+// Create a DatasourceReader from a TOML file. See TOMLDatasource.
+func TOMLFileDatasource(filename string) (DatasourceReader, error) {
+	return newFileDatasourceFromFile(filename, parseTOML)
+}
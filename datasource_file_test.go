@@ -0,0 +1,320 @@
+package inj
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////
+// Equivalent documents in each format
+///////////////////////////////////////////////////////////////
+
+const jsonDocument = `{
+	"server": {
+		"port": 9090,
+		"tls": {"cert": "/etc/cert.pem", "enabled": true}
+	},
+	"servers": [
+		{"host": "alpha", "weight": 1.5},
+		{"host": "beta", "weight": 2}
+	],
+	"tags": ["one", "two"],
+	"nothing": null
+}`
+
+const yamlDocument = `---
+# Server configuration
+server:
+  port: 9090
+  tls:
+    cert: "/etc/cert.pem"   # a comment
+    enabled: true
+servers:
+- host: alpha
+  weight: 1.5
+- host: 'beta'
+  weight: 2
+tags: [one, "two"]
+nothing: ~
+`
+
+const tomlDocument = `
+# Server configuration
+tags = [
+	"one",
+	'two', # trailing comma
+]
+
+[server]
+port = 9_090
+tls = { cert = "/etc/cert.pem", enabled = true }
+
+[[servers]]
+host = "alpha"
+weight = 1.5
+
+[[servers]]
+host = "beta"
+weight = 2
+`
+
+// Paths that should be found in all of the documents above
+var commonDocumentValues = map[string]interface{}{
+	"server.port":        9090,
+	"server.tls.cert":    "/etc/cert.pem",
+	"server.tls.enabled": true,
+	"servers.0.host":     "alpha",
+	"servers.1.host":     "beta",
+	"servers.0.weight":   1.5,
+	"tags.1":             "two",
+}
+
+// Paths that shouldn't be found in any of the documents above
+var missingDocumentPaths = []string{
+	"server.host",
+	"server.port.number",
+	"servers.2.host",
+	"servers.x.host",
+	"servers.-1.host",
+	"nothing",
+}
+
+///////////////////////////////////////////////////////////////
+// Unit tests
+///////////////////////////////////////////////////////////////
+
+// Compare a datasource value to an expected value, allowing for
+// numeric conversion
+func assertDatasourceValue(t *testing.T, d DatasourceReader, path string, expected interface{}) {
+
+	v, err := d.Read(path)
+
+	if err != nil {
+		t.Errorf("Read(%s): %s", path, err)
+		return
+	}
+
+	e := reflect.ValueOf(expected)
+	g := reflect.ValueOf(v)
+
+	if g.Type().ConvertibleTo(e.Type()) && g.Kind() != reflect.String {
+		g = g.Convert(e.Type())
+	}
+
+	if g.Interface() != expected {
+		t.Errorf("Read(%s): expected %v, got %v", path, expected, v)
+	}
+}
+
+// All three formats should produce the same values
+func Test_FileDatasources(t *testing.T) {
+
+	for name, ctor := range map[string]func() (DatasourceReader, error){
+		"json": func() (DatasourceReader, error) { return JSONDatasource(strings.NewReader(jsonDocument)) },
+		"yaml": func() (DatasourceReader, error) { return YAMLDatasource(strings.NewReader(yamlDocument)) },
+		"toml": func() (DatasourceReader, error) { return TOMLDatasource(strings.NewReader(tomlDocument)) },
+	} {
+		d, err := ctor()
+
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		for path, expected := range commonDocumentValues {
+			assertDatasourceValue(t, d, path, expected)
+		}
+
+		for _, path := range missingDocumentPaths {
+			if _, err := d.Read(path); err == nil {
+				t.Errorf("%s: Read(%s) didn't error", name, path)
+			}
+		}
+	}
+}
+
+// Malformed documents should return a ParseError with a line number
+func Test_FileDatasourcesParseErrors(t *testing.T) {
+
+	for i, c := range []struct {
+		ctor  func(string) error
+		input string
+		line  int
+	}{
+		{jsonCtor, "{\n\"a\": 1,\n}", 3},
+		{yamlCtor, "a: 1\n  b: 2", 2},
+		{yamlCtor, "a:\n  - 1\n  b: 2", 3},
+		{yamlCtor, "a: [1, 2", 1},
+		{yamlCtor, "a: 1\na: 2", 2},
+		{yamlCtor, "a: \"unterminated", 1},
+		{tomlCtor, "a = 1\na = 2", 2},
+		{tomlCtor, "a = \n", 1},
+		{tomlCtor, "[a]\nb = 1\n[a]", 3},
+		{tomlCtor, "a = [1, 2", 1},
+		{tomlCtor, "a = \"bad \\q escape\"", 1},
+		{tomlCtor, "a = 1 b = 2", 1},
+		{tomlCtor, "a = 1\nx = 9999999999999999999", 2},
+		{tomlCtor, "x = 0xffffffffffffffffff", 1},
+	} {
+		err := c.ctor(c.input)
+
+		perr, ok := err.(*ParseError)
+
+		if !ok {
+			t.Errorf("[%d] Expected a *ParseError, got %v", i, err)
+			continue
+		}
+
+		if g, e := perr.Line, c.line; g != e {
+			t.Errorf("[%d] Expected line %d, got %d (%s)", i, e, g, perr)
+		}
+	}
+}
+
+func jsonCtor(s string) error {
+	_, err := JSONDatasource(strings.NewReader(s))
+	return err
+}
+
+func yamlCtor(s string) error {
+	_, err := YAMLDatasource(strings.NewReader(s))
+	return err
+}
+
+func tomlCtor(s string) error {
+	_, err := TOMLDatasource(strings.NewReader(s))
+	return err
+}
+
+// YAML block scalars should follow the chomping rules
+func Test_YAMLBlockScalars(t *testing.T) {
+
+	d, err := YAMLDatasource(strings.NewReader(`
+literal: |
+  line one
+    indented
+  line three
+
+folded: >-
+  one
+  two
+
+  three
+kept: |+
+  text
+
+next: value
+`))
+
+	if err != nil {
+		t.Fatalf("YAMLDatasource: %s", err)
+	}
+
+	for path, expected := range map[string]interface{}{
+		"literal": "line one\n  indented\nline three\n",
+		"folded":  "one two\nthree",
+		"kept":    "text\n\n",
+		"next":    "value",
+	} {
+		assertDatasourceValue(t, d, path, expected)
+	}
+}
+
+// TOML strings and scalars should be decoded according to the spec
+func Test_TOMLValues(t *testing.T) {
+
+	d, err := TOMLDatasource(strings.NewReader(`
+basic = "tab\there \u00e9"
+literal = 'C:\path'
+multi = """
+one \
+  two"""
+raw = '''
+line'''
+hex = 0xff
+float = 6.5e-1
+date = 1979-05-27T07:32:00Z
+spaced = 1979-05-27 07:32:00-07:00
+local = 1979-05-27
+"quoted.key" = 1
+a.b.c = "dotted"
+`))
+
+	if err != nil {
+		t.Fatalf("TOMLDatasource: %s", err)
+	}
+
+	for path, expected := range map[string]interface{}{
+		"basic":   "tab\there é",
+		"literal": `C:\path`,
+		"multi":   "one two",
+		"raw":     "line",
+		"hex":     255,
+		"float":   0.65,
+		"date":    time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		"local":   "1979-05-27",
+		"a.b.c":   "dotted",
+	} {
+		v, _ := d.Read(path)
+
+		if tm, ok := v.(time.Time); ok {
+			if !tm.Equal(expected.(time.Time)) {
+				t.Errorf("Read(%s): expected %v, got %v", path, expected, tm)
+			}
+
+			continue
+		}
+
+		assertDatasourceValue(t, d, path, expected)
+	}
+
+	if v, _ := d.Read("spaced"); v == nil || v.(time.Time).Hour() != 7 {
+		t.Errorf("Unexpected date-time %v", v)
+	}
+
+	// Dotted keys can't be used to read quoted keys that contain dots
+	if _, err := d.Read("quoted.key"); err == nil {
+		t.Errorf("Read(quoted.key) didn't error")
+	}
+}
+
+// File datasources should be usable in a graph
+func Test_FileDatasourceInGraph(t *testing.T) {
+
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := ioutil.WriteFile(filename, []byte(yamlDocument), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	d, err := YAMLFileDatasource(filename)
+
+	if err != nil {
+		t.Fatalf("YAMLFileDatasource: %s", err)
+	}
+
+	dep := struct {
+		Port int     `inj:"server.port"`
+		Cert string  `inj:"server.tls.cert"`
+		Host string  `inj:"servers.1.host"`
+		W    float64 `inj:"servers.0.weight"`
+	}{}
+
+	g := newGraph()
+	g.AddDatasource(d)
+	g.Provide(&dep)
+
+	assertNoGraphErrors(t, g)
+
+	if dep.Port != 9090 || dep.Cert != "/etc/cert.pem" || dep.Host != "beta" || dep.W != 1.5 {
+		t.Errorf("Unexpected values %+v", dep)
+	}
+
+	if _, err := JSONFileDatasource(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("JSONFileDatasource didn't error for a missing file")
+	}
+}
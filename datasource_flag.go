@@ -0,0 +1,207 @@
+package inj
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+type flagDatasource struct {
+	fs *flag.FlagSet
+}
+
+// Create a DatasourceReader that reads values from command line flags, using
+// the datasource path as the flag name (so `inj:"port"` is met by -port=9090).
+// If the flag set is nil, flag.CommandLine is used.
+//
+// Only flags that were explicitly set on the command line are read, so that
+// their default values don't take precedence over other datasources. The flag
+// set must be parsed before the graph is connected.
+func FlagDatasource(fs *flag.FlagSet) DatasourceReader {
+
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	return &flagDatasource{fs}
+}
+
+// Implementation of the DatasourceReader interface
+func (d *flagDatasource) Read(path string) (interface{}, error) {
+
+	f := d.fs.Lookup(path)
+
+	if f == nil {
+		return nil, newNotFoundError("No flag named %s", path)
+	}
+
+	set := false
+
+	d.fs.Visit(func(f *flag.Flag) {
+		if f.Name == path {
+			set = true
+		}
+	})
+
+	if !set {
+		return nil, newNotFoundError("Flag %s wasn't set", path)
+	}
+
+	if g, ok := f.Value.(flag.Getter); ok {
+		return g.Get(), nil
+	}
+
+	return f.Value.String(), nil
+}
+
+// Register a flag for every datasource path in the struct tags of the supplied
+// objects, so that -help lists every field that can be configured. The type of
+// each flag is inferred from the type of its field, and the field's current
+// value is used as the default. If the flag set is nil, flag.CommandLine is
+// used.
+//
+// Paths that already have a flag are skipped, as are fields of types that
+// can't be parsed from a string. Only struct tags are read; to include fields
+// declared with Configure(), use RegisterFlags() to register the flags from the
+// graph instead.
+func RegisterObjectFlags(fs *flag.FlagSet, objs ...interface{}) error {
+
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	for i, obj := range objs {
+
+		if obj == nil {
+			return fmt.Errorf("Supplied argument %d is nil", i)
+		}
+
+		_, stype := getReflectionTypes(obj)
+
+		if stype.Kind() != reflect.Struct {
+			return fmt.Errorf("Supplied argument %d isn't a struct", i)
+		}
+
+		deps := make([]graphNodeDependency, 0)
+		basePath := emptyStructPath()
+		findDependencies(stype, &deps, &basePath)
+
+		registerFlags(fs, reflect.ValueOf(obj), stype, deps)
+	}
+
+	return nil
+}
+
+// Register a flag for each datasource path of a struct's dependencies
+func registerFlags(fs *flag.FlagSet, v reflect.Value, stype reflect.Type, deps []graphNodeDependency) {
+
+	for _, dep := range deps {
+
+		if !isFlagType(dep.Type) {
+			continue
+		}
+
+		// Use the current value of the field as the default
+		def := ""
+
+		if f := fieldByPath(v, dep.Path); f.IsValid() && f.CanInterface() && !zero(f) {
+			def = fmt.Sprint(f.Interface())
+		}
+
+		for _, path := range dep.DatasourcePaths {
+
+			if fs.Lookup(path) != nil {
+				continue
+			}
+
+			usage := fmt.Sprintf("Sets %s%s (%s)", stype.Name(), dep.Path, dep.Type)
+			fs.Var(&flagValue{typ: dep.Type, value: def, secret: dep.secret()}, path, usage)
+		}
+	}
+}
+
+// Get the value of a field by its struct path, or an invalid value
+// if the path doesn't exist
+func fieldByPath(v reflect.Value, path structPath) reflect.Value {
+
+	for !path.Empty() {
+
+		v = reflect.Indirect(v)
+
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+
+		var stub string
+		stub, path = path.Shift()
+		v = v.FieldByName(stub)
+	}
+
+	return v
+}
+
+// Returns true if a string can be parsed into the type, which
+// is true for every kind supported by convertString
+func isFlagType(t reflect.Type) bool {
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return false
+}
+
+// A flag.Value for an arbitrary type
+type flagValue struct {
+	typ    reflect.Type
+	value  string
+	parsed interface{}
+	secret bool
+}
+
+// Implementation of the flag.Value interface
+func (f *flagValue) String() string {
+
+	if f == nil {
+		return ""
+	}
+
+	if f.secret && f.value != "" {
+		return Redacted
+	}
+
+	return f.value
+}
+
+// Implementation of the flag.Value interface
+func (f *flagValue) Set(s string) error {
+
+	v, err := convertString(s, f.typ)
+
+	if err != nil {
+		return err
+	}
+
+	f.value = s
+	f.parsed = v.Interface()
+
+	return nil
+}
+
+// Implementation of the flag.Getter interface
+func (f *flagValue) Get() interface{} {
+	return f.parsed
+}
+
+// Boolean flags don't require a value
+func (f *flagValue) IsBoolFlag() bool {
+	return f.typ.Kind() == reflect.Bool
+}
+
+// Implementation of the SourceKeyer interface
+func (d *flagDatasource) SourceKey(path string) string {
+	return "-" + path
+}
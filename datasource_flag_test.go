@@ -0,0 +1,111 @@
+package inj
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////
+// A type with flag-sourced dependencies
+///////////////////////////////////////////////////////////////
+
+type flagDep struct {
+	Port    int           `inj:"port"`
+	Host    string        `inj:"host"`
+	Debug   bool          `inj:"debug"`
+	Timeout time.Duration `inj:"timeout"`
+	Nested  struct {
+		Name string `inj:"nested.name,name"`
+	}
+	Hello InterfaceOne `inj:"hello"`
+}
+
+///////////////////////////////////////////////////////////////
+// Unit tests
+///////////////////////////////////////////////////////////////
+
+// Flags should be registered for every datasource path
+func Test_RegisterObjectFlags(t *testing.T) {
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("host", "existing", "An existing flag")
+
+	dep := flagDep{Port: 8080}
+
+	if e := RegisterObjectFlags(fs, &dep); e != nil {
+		t.Fatalf("RegisterObjectFlags: %s", e)
+	}
+
+	for _, name := range []string{"port", "debug", "timeout", "nested.name", "name"} {
+		if fs.Lookup(name) == nil {
+			t.Errorf("Flag %s wasn't registered", name)
+		}
+	}
+
+	if g, e := fs.Lookup("port").DefValue, "8080"; g != e {
+		t.Errorf("Expected default %s, got %s", e, g)
+	}
+
+	if g, e := fs.Lookup("host").Usage, "An existing flag"; g != e {
+		t.Errorf("Existing flag was replaced")
+	}
+
+	if fs.Lookup("hello") != nil {
+		t.Errorf("Flag registered for an interface type")
+	}
+
+	// The help text should mention each field
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+
+	if !strings.Contains(usage.String(), "flagDep.Nested.Name") {
+		t.Errorf("Usage doesn't mention nested field: %s", usage.String())
+	}
+
+	if e := RegisterObjectFlags(fs, "not a struct"); e == nil {
+		t.Errorf("RegisterObjectFlags didn't error for a non-struct")
+	}
+}
+
+// Parsed flags should be injected; unset ones shouldn't
+func Test_FlagDatasourceInGraph(t *testing.T) {
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dep := flagDep{}
+
+	RegisterObjectFlags(fs, &dep)
+
+	if e := fs.Parse([]string{"--port=9090", "-debug", "-timeout", "1m", "-name=x", "-host=h"}); e != nil {
+		t.Fatalf("Parse: %s", e)
+	}
+
+	g := newGraph()
+	g.AddDatasource(FlagDatasource(fs))
+	g.Provide(&dep, &helloSayer{})
+
+	assertNoGraphErrors(t, g)
+
+	if dep.Port != 9090 || !dep.Debug || dep.Timeout != time.Minute || dep.Nested.Name != "x" || dep.Host != "h" {
+		t.Errorf("Unexpected values %+v", dep)
+	}
+
+	ds := FlagDatasource(fs)
+
+	if _, e := ds.Read("nested.name"); e == nil {
+		t.Errorf("Read didn't error for an unset flag")
+	}
+
+	if _, e := ds.Read("missing"); e == nil {
+		t.Errorf("Read didn't error for a missing flag")
+	}
+
+	if e := fs.Parse([]string{"-port=eighty"}); e == nil {
+		t.Errorf("Parse didn't error for a bad value")
+	}
+}
@@ -0,0 +1,302 @@
+package inj
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Conventional priorities for the layers of a LayeredDatasource. Higher
+// priorities are polled first.
+const (
+	PriorityDefaults = 100
+	PriorityFile     = 200
+	PriorityEnv      = 300
+	PriorityFlags    = 400
+)
+
+// A SourceKeyer is a DatasourceReader that can describe where in its
+// underlying source a datasource path is read from (for example, the
+// environment datasource maps "server.port" to "SERVER_PORT"). It's used
+// to report provenance.
+type SourceKeyer interface {
+	SourceKey(path string) string
+}
+
+// Provenance describes where a datasource value came from.
+type Provenance struct {
+	Path     string
+	Layer    string
+	Priority int
+	Key      string
+	Value    interface{}
+}
+
+// Implementation of the Stringer interface, eg.
+// "server.port=9090 came from env SERVER_PORT"
+func (p Provenance) String() string {
+
+	s := fmt.Sprintf("%s=%v came from %s", p.Path, p.Value, p.Layer)
+
+	if p.Key != "" {
+		s += " " + p.Key
+	}
+
+	return s
+}
+
+type datasourceLayer struct {
+	name     string
+	priority int
+	reader   DatasourceReader
+}
+
+// A LayeredDatasource is a DatasourceReader composed of other readers, each
+// with an explicit priority. Values are read from the highest priority layer
+// that has them, regardless of the order in which the layers were added
+// (layers with the same priority are polled in the order they were added).
+// This makes precedence explicit, unlike adding readers to a graph directly,
+// where they're polled in the order of the calls to AddDatasource().
+//
+//  ds := inj.NewLayeredDatasource().
+//      Add("flags", inj.PriorityFlags, inj.FlagDatasource(nil)).
+//      Add("env", inj.PriorityEnv, inj.EnvDatasource("")).
+//      Add("file", inj.PriorityFile, file)
+//
+// The layer that provided each value is recorded, and can be queried with
+// Provenance(); it's also included in the graph's errors about values that
+// can't be converted or that break validation rules. Changes to any layer
+// that's a WatchableDatasource (such as a FilePoller) are passed on to the
+// graph.
+type LayeredDatasource struct {
+	mu         sync.Mutex
+	layers     []datasourceLayer
+	provenance map[string]Provenance
+	secrets    map[string]bool
+	watchers   watchers
+	watching   bool
+	active     int
+}
+
+// Create a new, empty LayeredDatasource
+func NewLayeredDatasource() *LayeredDatasource {
+	return &LayeredDatasource{
+		provenance: make(map[string]Provenance),
+		secrets:    make(map[string]bool),
+	}
+}
+
+// Add a named layer with a given priority. Returns the LayeredDatasource, so
+// that calls can be chained.
+func (l *LayeredDatasource) Add(name string, priority int, r DatasourceReader) *LayeredDatasource {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Reads iterate over the layers without the lock, so they're replaced
+	// rather than sorted in place
+	layers := make([]datasourceLayer, len(l.layers), len(l.layers)+1)
+	copy(layers, l.layers)
+	layers = append(layers, datasourceLayer{name, priority, r})
+
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].priority > layers[j].priority
+	})
+
+	l.layers = layers
+
+	// Layers added after the datasource is watched are watched too
+	if w, ok := r.(WatchableDatasource); ok && l.watching {
+		l.forward(w)
+	}
+
+	return l
+}
+
+// Implementation of the WatchableDatasource interface. The channel receives
+// the paths that change in any watchable layer, and is closed once every
+// watchable layer has stopped watching (or immediately, if there are none).
+func (l *LayeredDatasource) Watch() <-chan []string {
+
+	l.mu.Lock()
+
+	if !l.watching {
+
+		l.watching = true
+
+		for _, layer := range l.layers {
+			if w, ok := layer.reader.(WatchableDatasource); ok {
+				l.forward(w)
+			}
+		}
+
+		if l.active == 0 {
+			l.watchers.close()
+		}
+	}
+
+	l.mu.Unlock()
+
+	return l.watchers.add()
+}
+
+// Pass on the changes to a layer, until it stops watching. Must be called
+// with the lock held.
+func (l *LayeredDatasource) forward(w WatchableDatasource) {
+
+	changes := w.Watch()
+	l.active++
+
+	go func() {
+		for paths := range changes {
+			l.watchers.notify(paths)
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.active--; l.active == 0 {
+			l.watchers.close()
+		}
+	}()
+}
+
+// Implementation of the DatasourceReader interface. If no layer has a value
+// for the path, the error lists every layer that was tried. If a layer fails
+// for any other reason, its error is returned without polling lower layers.
+func (l *LayeredDatasource) Read(path string) (interface{}, error) {
+	return l.ReadContext(context.Background(), path)
+}
+
+// Implementation of the ContextDatasourceReader interface. The context is
+// passed on to every layer.
+func (l *LayeredDatasource) ReadContext(ctx context.Context, path string) (interface{}, error) {
+
+	l.mu.Lock()
+	layers := l.layers
+	l.mu.Unlock()
+
+	tried := make([]string, 0, len(layers))
+
+	for _, layer := range layers {
+
+		v, err := readContext(ctx, layer.reader, path)
+		key := sourceKey(layer.reader, path)
+
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("Layer %s: %w", layer.name, err)
+		}
+
+		if err != nil {
+
+			if key != "" {
+				tried = append(tried, fmt.Sprintf("%s (%s)", layer.name, key))
+			} else {
+				tried = append(tried, layer.name)
+			}
+
+			continue
+		}
+
+		l.mu.Lock()
+		l.provenance[path] = Provenance{path, layer.name, layer.priority, key, l.redact(path, v)}
+		l.mu.Unlock()
+
+		return v, nil
+	}
+
+	return nil, newNotFoundError("Key %s not found in %s", path, strings.Join(tried, ", "))
+}
+
+// Find out which layer provided the most recently read value for a path.
+// Returns false if the path hasn't been read successfully.
+func (l *LayeredDatasource) Provenance(path string) (Provenance, bool) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, exists := l.provenance[path]
+
+	return p, exists
+}
+
+// Get the provenance of every value that's been read, ordered by path.
+func (l *LayeredDatasource) Provenances() []Provenance {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ps := make([]Provenance, 0, len(l.provenance))
+
+	for _, p := range l.provenance {
+		ps = append(ps, p)
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].Path < ps[j].Path
+	})
+
+	return ps
+}
+
+// Record that a path is secret, and redact its provenance. The layers are
+// told too, in case they record values.
+func (l *LayeredDatasource) markSecret(path string) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.secrets[path] = true
+
+	if p, exists := l.provenance[path]; exists {
+		p.Value = l.redact(path, p.Value)
+		l.provenance[path] = p
+	}
+
+	for _, layer := range l.layers {
+		markSecret(layer.reader, path)
+	}
+}
+
+// Secret values are recorded as a Secret, so they can't be printed
+func (l *LayeredDatasource) redact(path string, v interface{}) interface{} {
+
+	if _, ok := v.(Secret); ok || !l.secrets[path] {
+		return v
+	}
+
+	return Secret(fmt.Sprint(v))
+}
+
+// A reader that records where its values came from, such as a
+// LayeredDatasource
+type provenanceReader interface {
+	Provenance(path string) (Provenance, bool)
+}
+
+// Describe where the value of a path came from, if the reader knows, for
+// use at the end of an error about the value (which is redacted if the path
+// is secret)
+func provenanceOf(r DatasourceReader, path string) string {
+
+	if pr, ok := r.(provenanceReader); ok {
+		if p, exists := pr.Provenance(path); exists {
+			return " (" + p.String() + ")"
+		}
+	}
+
+	return ""
+}
+
+// Get the source key for a path from a reader, if it supports it
+func sourceKey(r DatasourceReader, path string) string {
+
+	if k, ok := r.(SourceKeyer); ok {
+		return k.SourceKey(path)
+	}
+
+	return ""
+}
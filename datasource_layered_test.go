@@ -0,0 +1,165 @@
+package inj
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////
+// Unit tests
+///////////////////////////////////////////////////////////////
+
+// Layers should be polled by priority, not insertion order
+func Test_LayeredDatasourcePriority(t *testing.T) {
+
+	t.Setenv("INJTEST_SERVER_PORT", "9090")
+
+	defaults := NewMockDatasourceReader(map[string]interface{}{
+		"server.port": 80,
+		"server.host": "localhost",
+	})
+
+	file, err := JSONDatasource(strings.NewReader(`{"server": {"port": 8080, "host": "example.com"}}`))
+
+	if err != nil {
+		t.Fatalf("JSONDatasource: %s", err)
+	}
+
+	l := NewLayeredDatasource().
+		Add("defaults", PriorityDefaults, defaults).
+		Add("env", PriorityEnv, EnvDatasource("INJTEST")).
+		Add("file", PriorityFile, file)
+
+	dep := struct {
+		Port int    `inj:"server.port"`
+		Host string `inj:"server.host"`
+	}{}
+
+	g := newGraph()
+	g.AddDatasource(l)
+	g.Provide(&dep)
+
+	assertNoGraphErrors(t, g)
+
+	if dep.Port != 9090 || dep.Host != "example.com" {
+		t.Errorf("Unexpected values %+v", dep)
+	}
+
+	p, exists := l.Provenance("server.port")
+
+	if !exists {
+		t.Fatalf("No provenance for server.port")
+	}
+
+	if g, e := p.String(), "server.port=9090 came from env INJTEST_SERVER_PORT"; g != e {
+		t.Errorf("Expected '%s', got '%s'", e, g)
+	}
+
+	if g, e := len(l.Provenances()), 2; g != e {
+		t.Errorf("Expected %d provenances, got %d", e, g)
+	}
+
+	if _, exists := l.Provenance("server.missing"); exists {
+		t.Errorf("Provenance exists for an unread path")
+	}
+}
+
+// Layers with the same priority should be polled in insertion order,
+// and errors should list every layer
+func Test_LayeredDatasourceTiesAndErrors(t *testing.T) {
+
+	l := NewLayeredDatasource().
+		Add("first", PriorityFile, NewMockDatasourceReader(map[string]interface{}{"a": 1})).
+		Add("second", PriorityFile, NewMockDatasourceReader(map[string]interface{}{"a": 2})).
+		Add("env", PriorityEnv, EnvDatasource("INJTEST"))
+
+	if v, _ := l.Read("a"); v != 1 {
+		t.Errorf("Expected 1, got %v", v)
+	}
+
+	_, err := l.Read("b")
+
+	if err == nil {
+		t.Fatalf("Read didn't error for a missing key")
+	}
+
+	if g, e := err.Error(), "Key b not found in env (INJTEST_B), first, second"; g != e {
+		t.Errorf("Expected '%s', got '%s'", e, g)
+	}
+}
+
+// Errors about layered values should say where they came from, without
+// revealing secrets
+func Test_LayeredDatasourceProvenanceInErrors(t *testing.T) {
+
+	t.Setenv("INJTEST_SERVER_PORT", "500")
+	t.Setenv("INJTEST_SERVER_TIMEOUT", "30")
+	t.Setenv("INJTEST_DB_PIN", "12345")
+
+	l := NewLayeredDatasource().
+		Add("defaults", PriorityDefaults, NewMockDatasourceReader(map[string]interface{}{"server.port": 80})).
+		Add("env", PriorityEnv, EnvDatasource("INJTEST"))
+
+	type config struct {
+		Port    int           `inj:"server.port,max=100"`
+		Timeout time.Duration `inj:"server.timeout"`
+		Pin     int           `inj:"db.pin,max=9999,secret"`
+	}
+
+	g := newGraph()
+	g.AddDatasource(NewCachedDatasource(l, 0))
+	g.Provide(&config{})
+
+	_, errs := g.Assert()
+	sort.Strings(errs)
+
+	expected := []string{
+		".Pin: Must be at most 9999 (db.pin=" + Redacted + " came from env INJTEST_DB_PIN)",
+		".Port: Must be at most 100 (server.port=500 came from env INJTEST_SERVER_PORT)",
+		`.Timeout: Couldn't convert server.timeout from datasource *inj.CachedDatasource: time: missing unit in duration "30" (server.timeout=30 came from env INJTEST_SERVER_TIMEOUT)`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+
+	for i, e := range expected {
+		if !strings.HasSuffix(errs[i], e) {
+			t.Errorf("Expected an error ending %q, got %q", e, errs[i])
+		}
+	}
+}
+
+// Layers should be safe to add while the datasource is being read
+func Test_LayeredDatasourceConcurrentAddAndRead(t *testing.T) {
+
+	l := NewLayeredDatasource().Add("first", 0, NewMockDatasourceReader())
+	stop, done := make(chan struct{}), make(chan struct{})
+
+	go func() {
+
+		defer close(done)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				l.Read("missing")
+			}
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		l.Add("layer", i%5, NewMockDatasourceReader(map[string]interface{}{"value": i}))
+	}
+
+	close(stop)
+	<-done
+
+	if v, err := l.Read("value"); err != nil || v != 4 {
+		t.Errorf("Expected the first layer with the highest priority, got %v (%v)", v, err)
+	}
+}
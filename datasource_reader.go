@@ -1,7 +1,69 @@
 package inj
 
+import (
+	"errors"
+	"fmt"
+)
+
 // A datasource reader provides an object from a datasource, identified by a
 // given string key. Refer to the documentation for the Datasource interface for more information.
+//
+// If the datasource doesn't have a value for the key, Read() must return ErrNotFound
+// (or an error that matches it with errors.Is), in which case the graph moves on to the
+// next datasource path or DatasourceReader. Any other error is treated as a failure of
+// the datasource, and is reported by Provide() and Assert().
 type DatasourceReader interface {
 	Read(string) (interface{}, error)
 }
+
+// A BatchDatasourceReader can read many keys in a single call, which is
+// much cheaper for remote sources. When a graph connects its dependencies,
+// it calls ReadMany() once with every datasource path in the graph, rather
+// than calling Read() for each field.
+//
+// Keys that the datasource doesn't have must be left out of the returned map.
+// If ReadMany() returns an error, the graph falls back to Read().
+type BatchDatasourceReader interface {
+	DatasourceReader
+	ReadMany(keys []string) (map[string]interface{}, error)
+}
+
+// The error returned by a DatasourceReader that doesn't have a value for a key.
+var ErrNotFound = errors.New("Key not found")
+
+// A DatasourceError describes a DatasourceReader that failed for a reason other
+// than a missing key (a broken connection to a remote service, for example).
+type DatasourceError struct {
+	Path   string
+	Reader DatasourceReader
+	Err    error
+}
+
+// Implementation of the error interface
+func (e *DatasourceError) Error() string {
+	return fmt.Sprintf("Datasource %T failed to read %s: %s", e.Reader, e.Path, e.Err)
+}
+
+// Get the underlying error
+func (e *DatasourceError) Unwrap() error {
+	return e.Err
+}
+
+// An error that describes a missing key, and which matches ErrNotFound
+type notFoundError struct {
+	message string
+}
+
+func newNotFoundError(format string, args ...interface{}) error {
+	return &notFoundError{fmt.Sprintf(format, args...)}
+}
+
+// Implementation of the error interface
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+// Allow errors.Is(err, ErrNotFound)
+func (e *notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
@@ -1,8 +1,12 @@
 package inj
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 ///////////////////////////////////////////////////////////////
@@ -34,7 +38,7 @@ func (d *MockDatasourceReader) Read(key string) (interface{}, error) {
 		return value, nil
 	}
 
-	return nil, fmt.Errorf("No stack entry for '%s'", key)
+	return nil, fmt.Errorf("No stack entry for '%s': %w", key, ErrNotFound)
 }
 
 ///////////////////////////////////////////////////////////////
@@ -47,6 +51,8 @@ func Test_TheDatasourceReaderWritesToTheDepdendency(t *testing.T) {
 	ds := newMockDataSourceWithValues(t)
 	g := NewGraph()
 
+	// The int is expressed as 16.01
+	g.SetLossyConversion(true)
 	g.AddDatasource(ds)
 	g.Provide(&dep)
 
@@ -59,4 +65,137 @@ func Test_TheDatasourceReaderWritesToTheDepdendency(t *testing.T) {
 	if dep.FuncValue == nil {
 		t.Errorf("Didn't get expected function instance")
 	}
+
+	if g, e := dep.IntValue, 16; g != e {
+		t.Errorf("Expected int %d, got %d", e, g)
+	}
+}
+
+///////////////////////////////////////////////////////////////
+// A DatasourceReader that always fails
+///////////////////////////////////////////////////////////////
+
+type brokenDatasourceReader struct{}
+
+func (d brokenDatasourceReader) Read(key string) (interface{}, error) {
+	return nil, errors.New("connection refused")
+}
+
+// Missing keys should fall through, but failures should be reported
+func Test_DatasourceReaderFailures(t *testing.T) {
+
+	dep := dataSourceDep{}
+	g := NewGraph()
+
+	if e := g.AddDatasource(newMockDataSourceWithValues(t)); e != nil {
+		t.Fatalf("AddDatasource: %s", e)
+	}
+
+	if e := g.Provide(&dep); e != nil {
+		t.Fatalf("Provide returned an error for missing keys: %s", e)
+	}
+
+	// A reader that fails before one that has the values
+	g = NewGraph(&dep)
+	e := g.AddDatasource(brokenDatasourceReader{}, newMockDataSourceWithValues(t))
+
+	var dserr *DatasourceError
+
+	if !errors.As(e, &dserr) {
+		t.Fatalf("Expected a *DatasourceError, got %v", e)
+	}
+
+	if dserr.Reader != (brokenDatasourceReader{}) || dserr.Path == "" {
+		t.Errorf("Unexpected datasource error %+v", dserr)
+	}
+
+	if v, m := g.Assert(); v || len(m) == 0 || !strings.Contains(m[0], "connection refused") {
+		t.Errorf("Assert didn't report the failure: %v", m)
+	}
+}
+
+// With several failures, Provide() should always return the same one
+func Test_DatasourceReaderFailuresAreDeterministic(t *testing.T) {
+
+	seen := make(map[string]bool)
+
+	for i := 0; i < 20; i++ {
+
+		g := NewGraph(&dataSourceDep{}, &writtenConfig{}, &flagDep{})
+		e := g.AddDatasource(brokenDatasourceReader{})
+
+		if e == nil {
+			t.Fatalf("Expected an error")
+		}
+
+		seen[e.Error()] = true
+	}
+
+	if len(seen) != 1 {
+		t.Errorf("Expected the same error every time, got %v", seen)
+	}
+}
+
+// Built-in readers should return ErrNotFound for missing keys
+func Test_DatasourceReadersNotFound(t *testing.T) {
+
+	file, _ := JSONDatasource(strings.NewReader("{}"))
+
+	for i, d := range []DatasourceReader{
+		EnvDatasource("INJTEST"),
+		file,
+		FlagDatasource(flag.NewFlagSet("test", flag.ContinueOnError)),
+		NewLayeredDatasource().Add("file", PriorityFile, file),
+	} {
+		if _, e := d.Read("not.here"); !errors.Is(e, ErrNotFound) {
+			t.Errorf("[%d] Expected ErrNotFound, got %v", i, e)
+		}
+	}
+
+	// Layers shouldn't mask failures
+	l := NewLayeredDatasource().
+		Add("file", PriorityFile, file).
+		Add("broken", PriorityEnv, brokenDatasourceReader{})
+
+	if _, e := l.Read("not.here"); e == nil || errors.Is(e, ErrNotFound) {
+		t.Errorf("Expected a failure, got %v", e)
+	}
+}
+
+// Prefixed structs should be populated from the datasource, leaving
+// fields without values untouched
+func Test_DatasourceReaderPopulatesPrefixedStruct(t *testing.T) {
+
+	dep := HasPrefixed{}
+	dep.DB.Port = 5432
+	dep.DB.Ignored = "unchanged"
+
+	g := newGraph()
+
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"db.host":            "localhost",
+		"database.max_conns": "10",
+		"db.timeout":         "5s",
+		"db.tls.cert":        "/etc/cert.pem",
+		"db.ignored":         "changed",
+		"secrets.db":         "hunter2",
+	}))
+
+	g.Provide(&dep, &helloSayer{})
+
+	assertNoGraphErrors(t, g)
+
+	db := dep.DB
+
+	if db.Host != "localhost" || db.Port != 5432 || db.MaxConns != 10 || db.Timeout != 5*time.Second ||
+		db.TLS.Cert != "/etc/cert.pem" || db.Ignored != "unchanged" || db.Pass != "hunter2" || db.Hello == nil {
+		t.Errorf("Unexpected values %+v", db)
+	}
+
+	// An int in the graph shouldn't be used for a prefixed field
+	g.Provide(80)
+
+	if dep.DB.Port != 5432 {
+		t.Errorf("Prefixed field was assigned from the graph")
+	}
 }
@@ -28,7 +28,7 @@ func (d *MockDatasource) Read(key string) (interface{}, error) {
 		return value, nil
 	}
 
-	return nil, fmt.Errorf("No stack entry for '%s'", key)
+	return nil, fmt.Errorf("No stack entry for '%s': %w", key, ErrNotFound)
 }
 
 func (d *MockDatasource) Write(key string, value interface{}) error {
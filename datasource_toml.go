@@ -0,0 +1,695 @@
+package inj
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A parser for TOML
+type tomlParser struct {
+	filename string
+	s        string
+	pos      int
+	line     int
+
+	// Tables defined by a header or an inline table, which
+	// can't be redefined
+	defined map[string]bool
+}
+
+func parseTOML(filename string, b []byte) (interface{}, error) {
+
+	p := &tomlParser{
+		filename: filename,
+		s:        strings.Replace(string(b), "\r\n", "\n", -1),
+		line:     1,
+		defined:  make(map[string]bool),
+	}
+
+	root := make(map[string]interface{})
+
+	if err := p.parse(root); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func (p *tomlParser) errorf(message string, args ...interface{}) error {
+	return &ParseError{Filename: p.filename, Line: p.line, Message: fmt.Sprintf(message, args...)}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *tomlParser) peek() byte {
+
+	if p.eof() {
+		return 0
+	}
+
+	return p.s[p.pos]
+}
+
+func (p *tomlParser) advance(n int) {
+
+	for i := 0; i < n && !p.eof(); i++ {
+
+		if p.s[p.pos] == '\n' {
+			p.line++
+		}
+
+		p.pos++
+	}
+}
+
+// Skip spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.advance(1)
+	}
+}
+
+// Skip whitespace, newlines and comments
+func (p *tomlParser) skipAll() {
+	for {
+		p.skipSpace()
+
+		switch p.peek() {
+		case '\n':
+			p.advance(1)
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.advance(1)
+	}
+}
+
+// Make sure that nothing but a comment follows on the current line
+func (p *tomlParser) endOfLine() error {
+
+	p.skipSpace()
+
+	if p.peek() == '#' {
+		p.skipComment()
+	}
+
+	if !p.eof() && p.peek() != '\n' {
+		return p.errorf("expected the end of the line, found %q", p.peek())
+	}
+
+	return nil
+}
+
+func (p *tomlParser) expect(c byte) error {
+
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+
+	p.advance(1)
+
+	return nil
+}
+
+// Parse the whole document
+func (p *tomlParser) parse(root map[string]interface{}) error {
+
+	current := root
+
+	for p.skipAll(); !p.eof(); p.skipAll() {
+
+		var err error
+
+		if p.peek() == '[' {
+			current, err = p.parseTableHeader(root)
+		} else {
+			err = p.parseKeyValue(current)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parse a [table] or [[array of tables]] header, and return the table
+// that subsequent keys belong to
+func (p *tomlParser) parseTableHeader(root map[string]interface{}) (map[string]interface{}, error) {
+
+	p.advance(1)
+	array := p.peek() == '['
+
+	if array {
+		p.advance(1)
+	}
+
+	p.skipSpace()
+	keys, err := p.parseKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+
+	if array {
+		if err := p.expect(']'); err != nil {
+			return nil, err
+		}
+	}
+
+	parent, err := p.descend(root, keys[:len(keys)-1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := keys[len(keys)-1]
+	existing, exists := parent[key]
+
+	if array {
+
+		var tables []interface{}
+
+		if exists {
+
+			a, ok := existing.([]interface{})
+
+			if !ok || !p.defined[fmt.Sprintf("%p", a)] {
+				return nil, p.errorf("%s isn't an array of tables", strings.Join(keys, "."))
+			}
+
+			tables = a
+		}
+
+		table := make(map[string]interface{})
+		tables = append(tables, table)
+		parent[key] = tables
+		p.defined[fmt.Sprintf("%p", tables)] = true
+		p.markDefined(table)
+
+		return table, nil
+	}
+
+	if exists {
+
+		table, ok := existing.(map[string]interface{})
+
+		if !ok || p.isDefined(table) {
+			return nil, p.errorf("%s is defined more than once", strings.Join(keys, "."))
+		}
+
+		p.markDefined(table)
+
+		return table, nil
+	}
+
+	table := make(map[string]interface{})
+	parent[key] = table
+	p.markDefined(table)
+
+	return table, nil
+}
+
+func (p *tomlParser) markDefined(t map[string]interface{}) {
+	p.defined[fmt.Sprintf("%p", t)] = true
+}
+
+func (p *tomlParser) isDefined(t map[string]interface{}) bool {
+	return p.defined[fmt.Sprintf("%p", t)]
+}
+
+// Find (or create) the table for a list of keys, relative to a parent
+func (p *tomlParser) descend(t map[string]interface{}, keys []string) (map[string]interface{}, error) {
+
+	for i, key := range keys {
+
+		switch v := t[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			t[key] = child
+			t = child
+		case map[string]interface{}:
+			t = v
+		case []interface{}:
+			// Arrays of tables refer to their last element
+			last, ok := v[len(v)-1].(map[string]interface{})
+
+			if !ok || !p.defined[fmt.Sprintf("%p", v)] {
+				return nil, p.errorf("%s isn't a table", strings.Join(keys[:i+1], "."))
+			}
+
+			t = last
+		default:
+			return nil, p.errorf("%s isn't a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+
+	return t, nil
+}
+
+// Parse a key = value pair into a table
+func (p *tomlParser) parseKeyValue(t map[string]interface{}) error {
+
+	keys, err := p.parseKey()
+
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+
+	if err := p.expect('='); err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	value, err := p.parseValue()
+
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(t, keys[:len(keys)-1])
+
+	if err != nil {
+		return err
+	}
+
+	key := keys[len(keys)-1]
+
+	if _, exists := parent[key]; exists {
+		return p.errorf("%s is defined more than once", strings.Join(keys, "."))
+	}
+
+	parent[key] = value
+
+	return nil
+}
+
+// Parse a (possibly dotted) key
+func (p *tomlParser) parseKey() ([]string, error) {
+
+	keys := make([]string, 0)
+
+	for {
+		var key string
+		var err error
+
+		switch c := p.peek(); {
+		case c == '"':
+			key, err = p.parseBasicString()
+		case c == '\'':
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+
+			for c := p.peek(); isTOMLBareKeyChar(c); c = p.peek() {
+				p.advance(1)
+			}
+
+			if p.pos == start {
+				return nil, p.errorf("expected a key")
+			}
+
+			key = p.s[start:p.pos]
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		p.skipSpace()
+
+		if p.peek() != '.' {
+			return keys, nil
+		}
+
+		p.advance(1)
+		p.skipSpace()
+	}
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			return p.parseMultilineString(`"""`)
+		}
+
+		return p.parseBasicString()
+	case c == '\'':
+		if strings.HasPrefix(p.s[p.pos:], "'''") {
+			return p.parseMultilineString("'''")
+		}
+
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case c == 0 || c == '\n':
+		return nil, p.errorf("expected a value")
+	}
+
+	return p.parseBareValue()
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+
+	p.advance(1)
+	var b strings.Builder
+
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.peek()
+
+		switch c {
+		case '"':
+			p.advance(1)
+			return b.String(), nil
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.advance(1)
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+
+	p.advance(1)
+	c := p.peek()
+	p.advance(1)
+
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+
+		if c == 'U' {
+			n = 8
+		}
+
+		if p.pos+n > len(p.s) {
+			return p.errorf("bad unicode escape")
+		}
+
+		r, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return p.errorf("bad unicode escape")
+		}
+
+		b.WriteRune(rune(r))
+		p.advance(n)
+	default:
+		return p.errorf("bad escape sequence \\%c", c)
+	}
+
+	return nil
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+
+	p.advance(1)
+	start := p.pos
+
+	for p.peek() != '\'' {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		p.advance(1)
+	}
+
+	s := p.s[start:p.pos]
+	p.advance(1)
+
+	return s, nil
+}
+
+func (p *tomlParser) parseMultilineString(delim string) (string, error) {
+
+	p.advance(3)
+
+	// A newline immediately after the opening delimiter is trimmed
+	if p.peek() == '\n' {
+		p.advance(1)
+	}
+
+	var b strings.Builder
+
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+
+		if strings.HasPrefix(p.s[p.pos:], delim) {
+
+			// Up to two quotes are allowed right before the delimiter
+			for i := 0; i < 2 && strings.HasPrefix(p.s[p.pos+1:], delim); i++ {
+				b.WriteByte(delim[0])
+				p.advance(1)
+			}
+
+			p.advance(3)
+
+			return b.String(), nil
+		}
+
+		c := p.peek()
+
+		if c == '\\' && delim == `"""` {
+
+			// A line ending backslash trims all following whitespace
+			rest := strings.TrimLeft(p.s[p.pos+1:], " \t")
+
+			if strings.HasPrefix(rest, "\n") {
+				p.advance(1)
+
+				for c := p.peek(); c == ' ' || c == '\t' || c == '\n'; c = p.peek() {
+					p.advance(1)
+				}
+
+				continue
+			}
+
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		b.WriteByte(c)
+		p.advance(1)
+	}
+}
+
+func (p *tomlParser) parseArray() (interface{}, error) {
+
+	p.advance(1)
+	a := make([]interface{}, 0)
+
+	for {
+		p.skipAll()
+
+		if p.peek() == ']' {
+			p.advance(1)
+			return a, nil
+		}
+
+		v, err := p.parseValue()
+
+		if err != nil {
+			return nil, err
+		}
+
+		a = append(a, v)
+		p.skipAll()
+
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in an array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+
+	p.advance(1)
+	t := make(map[string]interface{})
+	p.markDefined(t)
+
+	p.skipSpace()
+
+	if p.peek() == '}' {
+		p.advance(1)
+		return t, nil
+	}
+
+	for {
+		p.skipSpace()
+
+		if err := p.parseKeyValue(t); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case '}':
+			p.advance(1)
+			return t, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in an inline table")
+		}
+	}
+}
+
+// Parse a boolean, number or date-time
+func (p *tomlParser) parseBareValue() (interface{}, error) {
+
+	start := p.pos
+
+	for c := p.peek(); c != 0 && !strings.ContainsRune(" \t\n,]}#", rune(c)); c = p.peek() {
+		p.advance(1)
+	}
+
+	token := p.s[start:p.pos]
+
+	// Dates and times may be separated by a space
+	if len(token) == 10 && token[4] == '-' && p.peek() == ' ' &&
+		p.pos+1 < len(p.s) && p.s[p.pos+1] >= '0' && p.s[p.pos+1] <= '9' {
+
+		p.advance(1)
+
+		for c := p.peek(); c != 0 && !strings.ContainsRune(" \t\n,]}#", rune(c)); c = p.peek() {
+			p.advance(1)
+		}
+
+		token = token + "T" + p.s[start+11:p.pos]
+	}
+
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	// Date-times with an offset are the only kind that can be represented
+	// as a time.Time; local dates and times are kept as strings
+	if len(token) >= 10 && token[4] == '-' {
+
+		if t, err := time.Parse(time.RFC3339Nano, strings.Replace(token, "t", "T", 1)); err == nil {
+			return t, nil
+		}
+
+		for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02"} {
+			if _, err := time.Parse(layout, token); err == nil {
+				return token, nil
+			}
+		}
+	}
+
+	if len(token) >= 8 && token[2] == ':' {
+		if _, err := time.Parse("15:04:05.999999999", token); err == nil {
+			return token, nil
+		}
+	}
+
+	number := strings.Replace(token, "_", "", -1)
+
+	base := 10
+
+	if strings.HasPrefix(number, "0x") || strings.HasPrefix(number, "0o") || strings.HasPrefix(number, "0b") {
+		base = 0
+	}
+
+	// Only tokens with a fraction or an exponent are floats; integers that
+	// don't fit in 64 bits are errors, not floats
+	if base == 0 || !strings.ContainsAny(number, ".eE") {
+
+		i, err := strconv.ParseInt(number, base, 64)
+
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, p.errorf("%s overflows a 64-bit integer", token)
+		}
+
+		if err != nil {
+			return nil, p.errorf("bad value %s", token)
+		}
+
+		return i, nil
+	}
+
+	if f, err := strconv.ParseFloat(number, 64); err == nil && !strings.ContainsAny(number, "xXpP") {
+		return f, nil
+	}
+
+	return nil, p.errorf("bad value %s", token)
+}
@@ -0,0 +1,421 @@
+package inj
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A WatchableDatasource is a DatasourceReader whose values can change. The
+// channel returned by Watch() receives the datasource paths that have changed,
+// and is closed when the datasource stops watching.
+//
+// When a WatchableDatasource is added to a graph, the graph watches it for as
+// long as the channel is open. Each time paths change, the fields bound to
+// those paths (and only those fields) are re-assigned, and any affected nodes
+// that implement the Reloader interface are notified.
+//
+// Fields are re-assigned from a goroutine that the graph starts, which holds
+// the graph's lock but can't synchronise with anything else. Code that reads
+// a watched field from another goroutine while it might be reloaded races
+// with the reload; instead, implement Reloader and publish the new values in
+// OnReload() through something synchronised (such as a mutex, a channel or
+// sync/atomic), or only read the fields from within OnReload().
+type WatchableDatasource interface {
+	DatasourceReader
+	Watch() <-chan []string
+}
+
+// A Reloader is notified when some of its datasource-bound fields have been
+// re-assigned because a WatchableDatasource changed. OnReload() is called on
+// the goroutine that re-assigned the fields, after they've been assigned and
+// without the graph's lock held, so it can safely read them and hand them on
+// to the rest of the application:
+//
+//  func (s *Server) OnReload(paths []string) {
+//      s.mu.Lock()
+//      s.limit = s.Config.Limit
+//      s.mu.Unlock()
+//  }
+type Reloader interface {
+	OnReload(changedPaths []string)
+}
+
+// A set of watcher channels. Notifications never block: paths are added to
+// each watcher's pending set, and a goroutine per watcher delivers them when
+// the channel is read, so a slow reader receives several changes at once.
+type watchers struct {
+	mu       sync.Mutex
+	channels []*watcher
+	closed   bool
+}
+
+type watcher struct {
+	c       chan []string
+	wake    chan struct{}
+	pending []string
+}
+
+func (w *watchers) add() <-chan []string {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	c := make(chan []string)
+
+	if w.closed {
+		close(c)
+		return c
+	}
+
+	wt := &watcher{c: c, wake: make(chan struct{}, 1)}
+	w.channels = append(w.channels, wt)
+
+	go w.deliver(wt)
+
+	return c
+}
+
+// Send a watcher its pending paths until the set is closed
+func (w *watchers) deliver(wt *watcher) {
+
+	for range wt.wake {
+		w.flush(wt)
+	}
+
+	w.flush(wt)
+	close(wt.c)
+}
+
+// Send a watcher its pending paths (if any), without holding the lock
+func (w *watchers) flush(wt *watcher) {
+
+	w.mu.Lock()
+	paths := wt.pending
+	wt.pending = nil
+	w.mu.Unlock()
+
+	if len(paths) > 0 {
+		wt.c <- paths
+	}
+}
+
+func (w *watchers) notify(paths []string) {
+
+	if len(paths) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, wt := range w.channels {
+
+		for _, path := range paths {
+			wt.pending = appendUnique(wt.pending, path)
+		}
+
+		select {
+		case wt.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *watchers) close() {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	for _, wt := range w.channels {
+		close(wt.wake)
+	}
+
+	w.channels = nil
+	w.closed = true
+}
+
+///////////////////////////////////////////////
+// In-memory datasource
+///////////////////////////////////////////////
+
+// A MemoryDatasource is a WatchableDatasource that keeps its values in memory,
+// for use as a local key-value store, a source of defaults, or in tests.
+type MemoryDatasource struct {
+	mu       sync.Mutex
+	values   map[string]interface{}
+	watchers watchers
+}
+
+// Create a MemoryDatasource with some initial values (which may be nil)
+func NewMemoryDatasource(values map[string]interface{}) *MemoryDatasource {
+
+	m := &MemoryDatasource{values: make(map[string]interface{})}
+
+	for k, v := range values {
+		m.values[k] = v
+	}
+
+	return m
+}
+
+// Implementation of the DatasourceReader interface
+func (m *MemoryDatasource) Read(path string) (interface{}, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if v, exists := m.values[path]; exists {
+		return v, nil
+	}
+
+	return nil, newNotFoundError("Key %s not found", path)
+}
+
+// Implementation of the WatchableDatasource interface
+func (m *MemoryDatasource) Watch() <-chan []string {
+	return m.watchers.add()
+}
+
+// Set some values, and notify any watchers of the ones that changed
+func (m *MemoryDatasource) Set(values map[string]interface{}) {
+
+	m.mu.Lock()
+	changed := make([]string, 0, len(values))
+
+	for k, v := range values {
+
+		if old, exists := m.values[k]; !exists || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+
+		m.values[k] = v
+	}
+
+	m.mu.Unlock()
+
+	sort.Strings(changed)
+	m.watchers.notify(changed)
+}
+
+// Remove some values, and notify any watchers
+func (m *MemoryDatasource) Delete(paths ...string) {
+
+	m.mu.Lock()
+	changed := make([]string, 0, len(paths))
+
+	for _, k := range paths {
+		if _, exists := m.values[k]; exists {
+			delete(m.values, k)
+			changed = append(changed, k)
+		}
+	}
+
+	m.mu.Unlock()
+
+	m.watchers.notify(changed)
+}
+
+// Stop notifying watchers
+func (m *MemoryDatasource) Close() {
+	m.watchers.close()
+}
+
+///////////////////////////////////////////////
+// Polled files
+///////////////////////////////////////////////
+
+// A FilePoller is a WatchableDatasource that re-reads a configuration file
+// at a regular interval, and reports the paths that have changed.
+type FilePoller struct {
+	mu       sync.Mutex
+	filename string
+	parse    func(io.Reader) (DatasourceReader, error)
+	content  []byte
+	current  *fileDatasource
+	err      error
+	watchers watchers
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// Watch a configuration file for changes. The parse function must be one of
+// JSONDatasource, YAMLDatasource or TOMLDatasource:
+//
+//  ds, err := inj.PollFile("config.yaml", time.Second, inj.YAMLDatasource)
+//
+// The file is read immediately, and an error is returned if it can't be read or
+// parsed. Subsequently, if the file becomes malformed, the previous values are
+// kept, and the error is available from Err(). Call Close() to stop polling.
+func PollFile(filename string, interval time.Duration, parse func(io.Reader) (DatasourceReader, error)) (*FilePoller, error) {
+
+	p := &FilePoller{
+		filename: filename,
+		parse:    parse,
+		stop:     make(chan struct{}),
+	}
+
+	if _, err := p.load(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if changed, _ := p.load(); len(changed) > 0 {
+					p.watchers.notify(changed)
+				}
+			case <-p.stop:
+				p.watchers.close()
+				return
+			}
+		}
+	}()
+
+	return p, nil
+}
+
+// Read and parse the file if it's changed, and return the changed paths
+func (p *FilePoller) load() ([]string, error) {
+
+	b, err := ioutil.ReadFile(p.filename)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil && p.current != nil && bytes.Equal(b, p.content) {
+		return nil, nil
+	}
+
+	var d DatasourceReader
+
+	if err == nil {
+		d, err = p.parse(bytes.NewReader(b))
+	}
+
+	if err == nil {
+		if _, ok := d.(*fileDatasource); !ok {
+			err = fmt.Errorf("Can't poll %s: unsupported datasource %T", p.filename, d)
+		}
+	}
+
+	if perr, ok := err.(*ParseError); ok {
+		perr.Filename = p.filename
+	}
+
+	p.err = err
+
+	if err != nil {
+		return nil, err
+	}
+
+	next := d.(*fileDatasource)
+	next.filename = p.filename
+
+	var changed []string
+
+	if p.current != nil {
+		changed = diffTrees(p.current.data, next.data)
+	}
+
+	p.content = b
+	p.current = next
+
+	return changed, nil
+}
+
+// Implementation of the DatasourceReader interface
+func (p *FilePoller) Read(path string) (interface{}, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.current.Read(path)
+}
+
+// Implementation of the SourceKeyer interface
+func (p *FilePoller) SourceKey(path string) string {
+	return path + " in " + p.filename
+}
+
+// Implementation of the WatchableDatasource interface
+func (p *FilePoller) Watch() <-chan []string {
+	return p.watchers.add()
+}
+
+// Get the error from the most recent attempt to read the file, if any
+func (p *FilePoller) Err() error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+// Stop polling the file
+func (p *FilePoller) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+}
+
+// Find every path (including those of maps and arrays) whose value
+// differs between two trees
+func diffTrees(a, b interface{}) []string {
+
+	fa, fb := make(map[string]interface{}), make(map[string]interface{})
+	flattenTree("", a, fa)
+	flattenTree("", b, fb)
+
+	changed := make([]string, 0)
+
+	for k, v := range fa {
+		if w, exists := fb[k]; !exists || !reflect.DeepEqual(v, w) {
+			changed = append(changed, k)
+		}
+	}
+
+	for k := range fb {
+		if _, exists := fa[k]; !exists {
+			changed = append(changed, k)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+func flattenTree(prefix string, v interface{}, into map[string]interface{}) {
+
+	if prefix != "" {
+		into[prefix] = v
+		prefix += "."
+	}
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			flattenTree(prefix+k, child, into)
+		}
+	case []interface{}:
+		for i, child := range node {
+			flattenTree(prefix+strconv.Itoa(i), child, into)
+		}
+	}
+}
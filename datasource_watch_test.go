@@ -0,0 +1,366 @@
+package inj
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// A type that reports reloads on a channel
+type reloadTester struct {
+	Port    int    `inj:"server.port"`
+	Host    string `inj:"server.host"`
+	Other   string `inj:"other"`
+	reloads chan []string
+}
+
+func (r *reloadTester) OnReload(paths []string) {
+	sort.Strings(paths)
+	r.reloads <- paths
+}
+
+func waitForReload(t *testing.T, r *reloadTester) []string {
+
+	select {
+	case paths := <-r.reloads:
+		return paths
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for a reload")
+	}
+
+	return nil
+}
+
+// Changes to a memory datasource should only re-assign the affected fields
+func Test_MemoryDatasourceReload(t *testing.T) {
+
+	m := NewMemoryDatasource(map[string]interface{}{
+		"server.port": 80,
+		"server.host": "localhost",
+		"other":       "before",
+	})
+	defer m.Close()
+
+	r := &reloadTester{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(m)
+	g.Provide(r)
+
+	assertNoGraphErrors(t, g)
+
+	// Change a field by hand, so we can tell if it's re-assigned
+	r.Other = "changed locally"
+
+	m.Set(map[string]interface{}{"server.port": 8080, "server.host": "localhost"})
+
+	if g, e := waitForReload(t, r), []string{"server.port"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected reload of %v, got %v", e, g)
+	}
+
+	if r.Port != 8080 || r.Host != "localhost" || r.Other != "changed locally" {
+		t.Errorf("Unexpected values after reload %+v", r)
+	}
+
+	// Removing a key leaves the field unmet
+	m.Delete("other")
+
+	if g, e := waitForReload(t, r), []string{"other"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected reload of %v, got %v", e, g)
+	}
+
+	if valid, errs := g.Assert(); valid || len(errs) != 1 {
+		t.Errorf("Expected one error after deleting a key, got %v", errs)
+	}
+}
+
+type ruledReloadTester struct {
+	Port    int `inj:"port,max=100"`
+	reloads chan []string
+}
+
+func (r *ruledReloadTester) OnReload(paths []string) {
+	r.reloads <- paths
+}
+
+// Errors from a reload should replace the dependency's previous error
+func Test_ReloadReplacesErrors(t *testing.T) {
+
+	m := NewMemoryDatasource(map[string]interface{}{"port": 5})
+	defer m.Close()
+
+	r := &ruledReloadTester{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(m)
+	g.Provide(r)
+
+	assertNoGraphErrors(t, g)
+
+	for _, port := range []int{500, 600} {
+
+		m.Set(map[string]interface{}{"port": port})
+		<-r.reloads
+
+		if valid, errs := g.Assert(); valid || len(errs) != 1 {
+			t.Errorf("Expected one error for %d, got %v", port, errs)
+		}
+	}
+
+	m.Set(map[string]interface{}{"port": 5})
+	<-r.reloads
+
+	if valid, errs := g.Assert(); !valid || len(errs) != 0 || r.Port != 5 {
+		t.Errorf("Expected no errors after fixing the value, got %v (port %d)", errs, r.Port)
+	}
+}
+
+// A type that validates itself and reports reloads
+type reloadValidator struct {
+	Min     int `inj:"min"`
+	Max     int `inj:"max"`
+	reloads chan []string
+}
+
+func (r *reloadValidator) Validate() error {
+
+	if r.Min > r.Max {
+		return errors.New("Min is greater than max")
+	}
+
+	return nil
+}
+
+func (r *reloadValidator) OnReload(paths []string) {
+	r.reloads <- paths
+}
+
+// Nodes should validate themselves again when their fields are reloaded
+func Test_ReloadRevalidates(t *testing.T) {
+
+	m := NewMemoryDatasource(map[string]interface{}{"min": 1, "max": 2})
+	defer m.Close()
+
+	r := &reloadValidator{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(m)
+	g.Provide(r)
+
+	assertNoGraphErrors(t, g)
+
+	for _, max := range []int{0, -1} {
+
+		m.Set(map[string]interface{}{"max": max})
+		<-r.reloads
+
+		if valid, errs := g.Assert(); valid || len(errs) != 1 || errs[0] != "*inj.reloadValidator: Min is greater than max" {
+			t.Errorf("Expected one validation error for %d, got %v", max, errs)
+		}
+	}
+
+	m.Set(map[string]interface{}{"max": 5})
+	<-r.reloads
+
+	if valid, errs := g.Assert(); !valid || len(errs) != 0 {
+		t.Errorf("Expected no errors after fixing the value, got %v", errs)
+	}
+}
+
+// Changes to the layers of a LayeredDatasource should be passed on
+func Test_LayeredDatasourceReload(t *testing.T) {
+
+	env := NewMemoryDatasource(map[string]interface{}{"server.host": "example.com"})
+	file := NewMemoryDatasource(map[string]interface{}{"server.port": 80, "server.host": "localhost", "other": "x"})
+	late := NewMemoryDatasource(nil)
+
+	l := NewLayeredDatasource().
+		Add("env", PriorityEnv, env).
+		Add("file", PriorityFile, file).
+		Add("static", PriorityDefaults, NewMockDatasourceReader())
+
+	r := &reloadTester{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(l)
+	g.Provide(r)
+
+	assertNoGraphErrors(t, g)
+
+	file.Set(map[string]interface{}{"server.port": 8080})
+
+	if g, e := waitForReload(t, r), []string{"server.port"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected reload of %v, got %v", e, g)
+	}
+
+	if r.Port != 8080 || r.Host != "example.com" {
+		t.Errorf("Unexpected values after reload %+v", r)
+	}
+
+	// Layers added later are watched too
+	l.Add("late", PriorityFlags, late)
+	late.Set(map[string]interface{}{"other": "y"})
+
+	if g, e := waitForReload(t, r), []string{"other"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected reload of %v, got %v", e, g)
+	}
+
+	if r.Other != "y" {
+		t.Errorf("Expected the late layer's value, got %q", r.Other)
+	}
+
+	// The channel is closed once every layer has stopped watching
+	c := l.Watch()
+
+	env.Close()
+	file.Close()
+	late.Close()
+
+	select {
+	case _, open := <-c:
+		if open {
+			t.Errorf("Expected the channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Timed out waiting for the channel to close")
+	}
+}
+
+// Polled files should report changed paths, and keep their values when
+// Changes shouldn't block while a watcher is busy, but should still be
+// delivered once it reads them
+func Test_WatchersDontBlock(t *testing.T) {
+
+	m := NewMemoryDatasource(nil)
+	g := newGraph()
+	g.AddDatasource(m)
+
+	// The graph can't reload while it's locked, which used to block Set()
+	// once enough changes were queued, and then Watch() in turn
+	g.mu.Lock()
+
+	done := make(chan struct{})
+
+	go func() {
+
+		for i := 0; i < 100; i++ {
+			m.Set(map[string]interface{}{"a": i, "b": i})
+		}
+
+		m.Watch()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Changes blocked while the graph was locked")
+	}
+
+	g.mu.Unlock()
+
+	// An unread channel still receives every change
+	c := m.Watch()
+	m.Set(map[string]interface{}{"a": 1})
+	m.Set(map[string]interface{}{"b": 1})
+	m.Set(map[string]interface{}{"a": 2})
+
+	received := make(map[string]bool)
+
+	for len(received) < 2 {
+		select {
+		case paths := <-c:
+			for _, path := range paths {
+				received[path] = true
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Changes weren't delivered, got %v", received)
+		}
+	}
+
+	if !received["a"] || !received["b"] {
+		t.Errorf("Expected changes to a and b, got %v", received)
+	}
+}
+
+// the file becomes malformed
+func Test_FilePollerReload(t *testing.T) {
+
+	filename := filepath.Join(t.TempDir(), "config.json")
+
+	write := func(s string) {
+		if err := ioutil.WriteFile(filename, []byte(s), 0600); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+
+	write(`{"server": {"port": 80, "host": "localhost"}, "other": "x"}`)
+
+	p, err := PollFile(filename, 5*time.Millisecond, JSONDatasource)
+
+	if err != nil {
+		t.Fatalf("PollFile: %s", err)
+	}
+
+	defer p.Close()
+
+	r := &reloadTester{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(p)
+	g.Provide(r)
+
+	assertNoGraphErrors(t, g)
+
+	write(`{"server": {"port": 81, "host": "localhost"}, "other": "x"}`)
+
+	if g, e := waitForReload(t, r), []string{"server.port"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected reload of %v, got %v", e, g)
+	}
+
+	if r.Port != 81 {
+		t.Errorf("Expected port 81, got %d", r.Port)
+	}
+
+	write(`{"server": `)
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for p.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if p.Err() == nil {
+		t.Errorf("Expected an error for a malformed file")
+	}
+
+	if v, err := p.Read("server.port"); err != nil || v != 81.0 {
+		t.Errorf("Expected the previous value to be kept, got %v (%v)", v, err)
+	}
+
+	if _, err := PollFile(filepath.Join(t.TempDir(), "missing.json"), time.Second, JSONDatasource); err == nil {
+		t.Errorf("PollFile didn't error for a missing file")
+	}
+}
+
+// Trees should be diffed at every level
+func Test_DiffTrees(t *testing.T) {
+
+	a := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1.0, "c": 2.0},
+		"d": []interface{}{"x"},
+	}
+
+	b := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1.0, "c": 3.0},
+		"d": []interface{}{"x", "y"},
+	}
+
+	if g, e := diffTrees(a, b), []string{"a", "a.c", "d", "d.1"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("Expected %v, got %v", e, g)
+	}
+}
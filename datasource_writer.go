@@ -2,6 +2,22 @@ package inj
 
 // A datasource reader sends an object to a datasource, identified by a
 // given string key. Refer to the documentation for the Datasource interface for more information.
+//
+// Three implementations are provided: JSONFileWriter() and EnvFileWriter() keep files up to date
+// with the effective configuration, and AuditWriter() logs each value as it's assigned.
+//
+// A graph only calls Write() when a value has changed since it was last written, so reconnecting
+// the graph doesn't repeat writes. If Write() returns an error, it's reported by Assert(), and the
+// value is written again the next time the graph is connected.
 type DatasourceWriter interface {
 	Write(string, interface{}) error
 }
+
+// A DatasourceChangeWriter is a DatasourceWriter that wants to know the previous
+// value of a key as well as the new one. If a writer implements it, the graph
+// calls WriteChange() instead of Write(). The previous value is nil the first
+// time a key is written.
+type DatasourceChangeWriter interface {
+	DatasourceWriter
+	WriteChange(key string, previous, value interface{}) error
+}
@@ -0,0 +1,366 @@
+package inj
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A WriterOption configures the behaviour of one of the built-in
+// DatasourceWriters
+type WriterOption func(*writerConfig)
+
+type writerConfig struct {
+	redact     []string
+	provenance *LayeredDatasource
+	env        []EnvOption
+}
+
+func newWriterConfig(opts []WriterOption) writerConfig {
+
+	var c writerConfig
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// Replace the values of some datasource paths with Redacted. Patterns are
+// matched with path.Match, so "*.password" redacts "db.password". Values of
+// secret fields are always redacted (see Secret).
+func RedactKeys(patterns ...string) WriterOption {
+	return func(c *writerConfig) {
+		c.redact = append(c.redact, patterns...)
+	}
+}
+
+// Record where each value came from in audit records, using the provenance
+// tracked by a LayeredDatasource.
+func AuditProvenance(l *LayeredDatasource) WriterOption {
+	return func(c *writerConfig) {
+		c.provenance = l
+	}
+}
+
+// Name the variables in a .env file using the same options as an
+// environment datasource, so that the file can be read back by it.
+func EnvNaming(opts ...EnvOption) WriterOption {
+	return func(c *writerConfig) {
+		c.env = append(c.env, opts...)
+	}
+}
+
+// Apply any redaction to a value
+func (c writerConfig) value(p string, v interface{}) interface{} {
+
+	for _, pattern := range c.redact {
+		if matched, _ := path.Match(pattern, p); matched {
+			return Redacted
+		}
+	}
+
+	return v
+}
+
+// Write a file atomically, so that readers never see part of it
+func writeFileAtomic(filename string, data []byte) error {
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename))
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+///////////////////////////////////////////////
+// JSON files
+///////////////////////////////////////////////
+
+type jsonFileWriter struct {
+	mu       sync.Mutex
+	filename string
+	config   writerConfig
+	values   map[string]interface{}
+}
+
+// Create a DatasourceWriter that keeps a JSON file up to date with every value
+// written to it, as an object nested by datasource path. Since the file can be
+// read by JSONFileDatasource(), it's a convenient way to capture the effective
+// configuration of an application and reproduce it elsewhere.
+//
+// The whole file is rewritten for each value.
+func JSONFileWriter(filename string, opts ...WriterOption) DatasourceWriter {
+
+	return &jsonFileWriter{
+		filename: filename,
+		config:   newWriterConfig(opts),
+		values:   make(map[string]interface{}),
+	}
+}
+
+// Implementation of the DatasourceWriter interface
+func (w *jsonFileWriter) Write(p string, v interface{}) error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.values[p] = jsonValue(w.config.value(p, v))
+
+	// Build a tree, in path order so that nested values replace
+	// any scalar parents
+	paths := make([]string, 0, len(w.values))
+
+	for k := range w.values {
+		paths = append(paths, k)
+	}
+
+	sort.Strings(paths)
+
+	tree := make(map[string]interface{})
+
+	for _, k := range paths {
+
+		parts := strings.Split(k, ".")
+		node := tree
+
+		for _, part := range parts[:len(parts)-1] {
+
+			child, ok := node[part].(map[string]interface{})
+
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+
+			node = child
+		}
+
+		node[parts[len(parts)-1]] = w.values[k]
+	}
+
+	b, err := json.MarshalIndent(tree, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(w.filename, append(b, '\n'))
+}
+
+// Get a value that can be encoded as JSON, and read back by a datasource
+func jsonValue(v interface{}) interface{} {
+
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return v
+}
+
+///////////////////////////////////////////////
+// .env files
+///////////////////////////////////////////////
+
+type envFileWriter struct {
+	mu       sync.Mutex
+	filename string
+	config   writerConfig
+	env      *envDatasource
+	values   map[string]string
+}
+
+// Create a DatasourceWriter that keeps a .env file up to date with every value
+// written to it. Variable names are derived from datasource paths in the same
+// way as EnvDatasource() (use EnvNaming() to pass it the same options), and
+// lines are sorted by name:
+//
+//  APP_DB_HOST=localhost
+//  APP_GREETING="hello world"
+//
+// Slices are written as comma-separated lists, and values that contain spaces
+// or special characters are quoted (in single quotes, if they contain $ or
+// backticks, so that they aren't expanded).
+func EnvFileWriter(filename, prefix string, opts ...WriterOption) DatasourceWriter {
+
+	w := &envFileWriter{
+		filename: filename,
+		config:   newWriterConfig(opts),
+		values:   make(map[string]string),
+	}
+
+	w.env = EnvDatasource(prefix, w.config.env...).(*envDatasource)
+
+	return w
+}
+
+// Implementation of the DatasourceWriter interface
+func (w *envFileWriter) Write(p string, v interface{}) error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.values[w.env.SourceKey(p)] = envValue(w.config.value(p, v))
+
+	keys := make([]string, 0, len(w.values))
+
+	for k := range w.values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, w.values[k])
+	}
+
+	return writeFileAtomic(w.filename, []byte(b.String()))
+}
+
+// Render a value for a .env file
+func envValue(v interface{}) string {
+
+	var s string
+
+	rv := reflect.ValueOf(v)
+
+	if rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+
+		parts := make([]string, rv.Len())
+
+		for i := range parts {
+			parts[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+
+		s = strings.Join(parts, ",")
+	} else if v != nil {
+		s = fmt.Sprint(v)
+	}
+
+	if !strings.ContainsAny(s, " \t\r\n#\"'\\$=`") {
+		return s
+	}
+
+	// Shells and most .env loaders expand variables and commands inside
+	// double quotes, but not inside single quotes
+	if strings.ContainsAny(s, "$`") {
+
+		if !strings.ContainsAny(s, "'\r\n") {
+			return "'" + s + "'"
+		}
+
+		return strings.NewReplacer("$", "\\$", "`", "\\`").Replace(strconv.Quote(s))
+	}
+
+	return strconv.Quote(s)
+}
+
+///////////////////////////////////////////////
+// Audit logs
+///////////////////////////////////////////////
+
+// An AuditRecord describes a value written to an audit log. Each record is
+// written as a single line of JSON.
+type AuditRecord struct {
+	Time   time.Time   `json:"time"`
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Value  interface{} `json:"value"`
+	Layer  string      `json:"layer,omitempty"`
+	Source string      `json:"source,omitempty"`
+}
+
+// Writers that record the type of each value (such as the audit writer) are
+// told it separately by the graph, since secret values are redacted before
+// they're written
+type typedWriter interface {
+	writeTyped(path string, typ reflect.Type, value interface{}) error
+}
+
+type auditWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	config writerConfig
+	now    func() time.Time
+}
+
+// Create a DatasourceWriter that appends an AuditRecord to an io.Writer for
+// every value written to it. Use AuditProvenance() to record which layer of a
+// LayeredDatasource each value came from.
+func AuditWriter(w io.Writer, opts ...WriterOption) DatasourceWriter {
+
+	return &auditWriter{
+		w:      w,
+		config: newWriterConfig(opts),
+		now:    time.Now,
+	}
+}
+
+// Implementation of the DatasourceWriter interface
+func (a *auditWriter) Write(p string, v interface{}) error {
+	return a.writeTyped(p, reflect.TypeOf(v), v)
+}
+
+// Implementation of the typedWriter interface
+func (a *auditWriter) writeTyped(p string, typ reflect.Type, v interface{}) error {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r := AuditRecord{
+		Time:  a.now(),
+		Path:  p,
+		Type:  fmt.Sprint(typ),
+		Value: jsonValue(a.config.value(p, v)),
+	}
+
+	if a.config.provenance != nil {
+		if prov, ok := a.config.provenance.Provenance(p); ok {
+			r.Layer = prov.Layer
+			r.Source = prov.Key
+		}
+	}
+
+	b, err := json.Marshal(r)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = a.w.Write(append(b, '\n'))
+
+	return err
+}
@@ -0,0 +1,212 @@
+package inj
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type writtenConfig struct {
+	Host     string        `inj:"db.host"`
+	Port     int           `inj:"db.port"`
+	Password string        `inj:"db.password"`
+	Token    string        `inj:"api.token,secret"`
+	Greeting string        `inj:"greeting"`
+	Timeout  time.Duration `inj:"timeout"`
+	Tags     []string      `inj:"tags"`
+}
+
+func writtenValues() map[string]interface{} {
+
+	return map[string]interface{}{
+		"db.host":     "localhost",
+		"db.port":     5432,
+		"db.password": "hunter2",
+		"api.token":   "hunter2",
+		"greeting":    "hello world",
+		"timeout":     "30s",
+		"tags":        "one,two",
+	}
+}
+
+// The JSON dump should be readable by a JSON datasource
+func Test_JSONFileWriter(t *testing.T) {
+
+	filename := filepath.Join(t.TempDir(), "effective.json")
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(writtenValues()), JSONFileWriter(filename, RedactKeys("*.password")))
+	g.Provide(&writtenConfig{})
+
+	assertNoGraphErrors(t, g)
+
+	d, err := JSONFileDatasource(filename)
+
+	if err != nil {
+		t.Fatalf("JSONFileDatasource: %s", err)
+	}
+
+	for path, expected := range map[string]interface{}{
+		"db.host":     "localhost",
+		"db.port":     5432,
+		"db.password": Redacted,
+		"api.token":   Redacted,
+		"timeout":     "30s",
+		"tags.1":      "two",
+	} {
+		assertDatasourceValue(t, d, path, expected)
+	}
+
+	// The dump can be used to configure another graph
+	c := &writtenConfig{}
+
+	g = newGraph()
+	g.AddDatasource(d)
+	g.Provide(c)
+
+	if c.Port != 5432 || c.Timeout != 30*time.Second || len(c.Tags) != 2 {
+		t.Errorf("Unexpected values read from the dump %+v", c)
+	}
+}
+
+// The .env file should be sorted, quoted and redacted
+func Test_EnvFileWriter(t *testing.T) {
+
+	filename := filepath.Join(t.TempDir(), ".env")
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(writtenValues()), EnvFileWriter(filename, "APP", RedactKeys("db.password")))
+	g.Provide(&writtenConfig{})
+
+	b, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+
+	expected := `APP_API_TOKEN=[REDACTED]
+APP_DB_HOST=localhost
+APP_DB_PASSWORD=[REDACTED]
+APP_DB_PORT=5432
+APP_GREETING="hello world"
+APP_TAGS=one,two
+APP_TIMEOUT=30s
+`
+
+	if g := string(b); g != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, g)
+	}
+
+	// Naming options are shared with the environment datasource
+	w := EnvFileWriter(filename, "app", EnvNaming(EnvPreserveCase(), EnvSeparator("__")))
+	w.Write("db.host", "localhost")
+
+	if b, _ := ioutil.ReadFile(filename); string(b) != "app__db__host=localhost\n" {
+		t.Errorf("Unexpected file %q", b)
+	}
+}
+
+// Values with $ or backticks should read back unchanged, without expansion
+func Test_EnvFileWriterQuoting(t *testing.T) {
+
+	sh, err := exec.LookPath("sh")
+
+	if err != nil {
+		t.Skip("No shell to read the file back with")
+	}
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	w := EnvFileWriter(filename, "APP")
+
+	for i, value := range []string{"a$b", "it's `x` $HOME", "plain", "a b"} {
+
+		w.Write("value", value)
+
+		out, err := exec.Command(sh, "-c", `set -a; . "$0"; printf %s "$APP_VALUE"`, filename).Output()
+
+		if err != nil {
+			t.Fatalf("[%d] sh: %s", i, err)
+		}
+
+		if g := string(out); g != value {
+			b, _ := ioutil.ReadFile(filename)
+			t.Errorf("[%d] Expected %q to read back, got %q from %q", i, value, g, b)
+		}
+	}
+
+	if g, e := envValue("a$b"), "'a$b'"; g != e {
+		t.Errorf("Expected %s, got %s", e, g)
+	}
+}
+
+// Audit records should include the provenance of each value
+func Test_AuditWriter(t *testing.T) {
+
+	layered := NewLayeredDatasource().
+		Add("defaults", PriorityDefaults, NewMockDatasourceReader(writtenValues())).
+		Add("env", PriorityEnv, NewMockDatasourceReader(map[string]interface{}{"db.port": "6543"}))
+
+	var buf bytes.Buffer
+
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	w := AuditWriter(&buf, AuditProvenance(layered), RedactKeys("*.password"))
+	w.(*auditWriter).now = func() time.Time { return when }
+
+	g := newGraph()
+	g.AddDatasource(layered, w)
+	g.Provide(&writtenConfig{})
+
+	records := make(map[string]AuditRecord)
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+
+		var r AuditRecord
+
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Unmarshal(%s): %s", line, err)
+		}
+
+		records[r.Path] = r
+	}
+
+	if g, e := len(records), len(writtenValues()); g != e {
+		t.Errorf("Expected %d records, got %d", e, g)
+	}
+
+	port := records["db.port"]
+
+	if port.Type != "int" || port.Value != 6543.0 || port.Layer != "env" || !port.Time.Equal(when) {
+		t.Errorf("Unexpected record %+v", port)
+	}
+
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("The audit log leaks a secret: %s", buf.String())
+	}
+}
+
+// Audit records of secrets should have the type of the field, not Redacted
+func Test_AuditWriterSecretType(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	g := newGraph()
+	g.AddDatasource(AuditWriter(&buf), NewMockDatasourceReader(map[string]interface{}{"pin": 1234}))
+	g.Provide(&struct {
+		Pin int `inj:"pin,secret"`
+	}{})
+
+	var r AuditRecord
+
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Unmarshal(%s): %s", buf.String(), err)
+	}
+
+	if r.Type != "int" || r.Value != Redacted {
+		t.Errorf("Unexpected record %+v", r)
+	}
+}
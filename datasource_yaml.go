@@ -0,0 +1,547 @@
+package inj
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A single line of YAML input
+type yamlLine struct {
+	number  int
+	raw     string
+	indent  int
+	content string
+}
+
+// Returns true if the line has no content (other than a comment)
+func (l yamlLine) blank() bool {
+	return l.content == ""
+}
+
+// A parser for a practical subset of YAML
+type yamlParser struct {
+	filename string
+	lines    []yamlLine
+	pos      int
+}
+
+func parseYAML(filename string, b []byte) (interface{}, error) {
+
+	p := &yamlParser{filename: filename}
+
+	if err := p.split(string(b)); err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+
+	if p.pos >= len(p.lines) {
+		return map[string]interface{}{}, nil
+	}
+
+	v, err := p.parseBlock(p.lines[p.pos].indent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected content")
+	}
+
+	return v, nil
+}
+
+func (p *yamlParser) errorf(l yamlLine, message string, args ...interface{}) error {
+
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+
+	return &ParseError{Filename: p.filename, Line: l.number, Message: message}
+}
+
+// Break the input into lines, measuring indentation and removing comments
+func (p *yamlParser) split(s string) error {
+
+	for i, raw := range strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n") {
+
+		l := yamlLine{number: i + 1, raw: raw}
+		trimmed := strings.TrimLeft(raw, " ")
+		l.indent = len(raw) - len(trimmed)
+		l.content = strings.TrimSpace(stripYAMLComment(trimmed))
+
+		if strings.HasPrefix(trimmed, "\t") {
+			return p.errorf(l, "tabs can't be used for indentation")
+		}
+
+		// Document markers are ignored
+		if l.indent == 0 && (l.content == "---" || l.content == "...") {
+			l.content = ""
+		}
+
+		if l.indent == 0 && strings.HasPrefix(l.content, "%") {
+			return p.errorf(l, "directives aren't supported")
+		}
+
+		p.lines = append(p.lines, l)
+	}
+
+	return nil
+}
+
+// Remove a trailing comment from a line, ignoring hashes in quotes
+func stripYAMLComment(s string) string {
+
+	var quote rune
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && (i == 0 || strings.ContainsRune(" [{,:", rune(s[i-1]))):
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+
+	return s
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].blank() {
+		p.pos++
+	}
+}
+
+// Returns true if the content of a line is a sequence entry
+func isYAMLSequenceEntry(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// Parse a block mapping or sequence at a given indentation
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+
+	if isYAMLSequenceEntry(p.lines[p.pos].content) {
+		return p.parseSequence(indent)
+	}
+
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+
+	seq := make([]interface{}, 0)
+
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+
+		l := p.lines[p.pos]
+
+		if l.indent < indent || (l.indent == indent && !isYAMLSequenceEntry(l.content)) {
+			break
+		}
+
+		if l.indent > indent {
+			return nil, p.errorf(l, "bad indentation of a sequence entry")
+		}
+
+		rest := strings.TrimSpace(l.content[1:])
+
+		switch {
+		case rest == "":
+			// The entry is a nested block (or null)
+			p.pos++
+			v, err := p.parseNested(indent, false)
+
+			if err != nil {
+				return nil, err
+			}
+
+			seq = append(seq, v)
+		case isYAMLSequenceEntry(rest) || yamlMappingKey(rest) >= 0:
+			// The entry is a mapping or sequence that starts on the same
+			// line, so treat it as though the dash were whitespace
+			p.lines[p.pos].indent = l.indent + len(l.content) - len(rest)
+			p.lines[p.pos].content = rest
+
+			v, err := p.parseBlock(p.lines[p.pos].indent)
+
+			if err != nil {
+				return nil, err
+			}
+
+			seq = append(seq, v)
+		default:
+			p.pos++
+			v, err := p.parseScalar(l, rest)
+
+			if err != nil {
+				return nil, err
+			}
+
+			seq = append(seq, v)
+		}
+	}
+
+	return seq, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+
+	m := make(map[string]interface{})
+
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+
+		l := p.lines[p.pos]
+
+		if l.indent < indent {
+			break
+		}
+
+		if l.indent > indent {
+			return nil, p.errorf(l, "bad indentation of a mapping entry")
+		}
+
+		// A sequence at the same level as its parent's key
+		// ends the mapping
+		if isYAMLSequenceEntry(l.content) {
+			break
+		}
+
+		i := yamlMappingKey(l.content)
+
+		if i < 0 {
+			return nil, p.errorf(l, "expected a mapping entry")
+		}
+
+		key, err := p.parseKey(l, strings.TrimSpace(l.content[:i]))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := m[key]; exists {
+			return nil, p.errorf(l, "duplicate key %s", key)
+		}
+
+		rest := strings.TrimSpace(l.content[i+1:])
+		p.pos++
+
+		switch {
+		case rest == "":
+			m[key], err = p.parseNested(indent, true)
+		case rest[0] == '|' || rest[0] == '>':
+			m[key], err = p.parseBlockScalar(l, rest)
+		default:
+			m[key], err = p.parseScalar(l, rest)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Parse the value of an entry that's on the following lines. Sequences
+// may be at the same indentation as a mapping key.
+func (p *yamlParser) parseNested(indent int, sameLevelSequence bool) (interface{}, error) {
+
+	p.skipBlank()
+
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	l := p.lines[p.pos]
+
+	if l.indent > indent {
+		return p.parseBlock(l.indent)
+	}
+
+	if sameLevelSequence && l.indent == indent && isYAMLSequenceEntry(l.content) {
+		return p.parseSequence(indent)
+	}
+
+	return nil, nil
+}
+
+// Find the colon that separates a mapping key from its value, or -1
+func yamlMappingKey(s string) int {
+
+	if len(s) == 0 || s[0] == '[' || s[0] == '{' {
+		return -1
+	}
+
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case i == 0 && (s[i] == '"' || s[i] == '\''):
+			quote = s[i]
+		case s[i] == ':' && (i == len(s)-1 || s[i+1] == ' '):
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (p *yamlParser) parseKey(l yamlLine, s string) (string, error) {
+
+	if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+
+		v, err := p.parseScalar(l, s)
+
+		if err != nil {
+			return "", err
+		}
+
+		return v.(string), nil
+	}
+
+	return s, nil
+}
+
+// Parse a literal (|) or folded (>) block scalar
+func (p *yamlParser) parseBlockScalar(l yamlLine, header string) (interface{}, error) {
+
+	folded := header[0] == '>'
+	chomp := strings.TrimLeft(header[1:], "123456789")
+
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return nil, p.errorf(l, "bad block scalar header %s", header)
+	}
+
+	lines := make([]string, 0)
+	indent := -1
+
+	for ; p.pos < len(p.lines); p.pos++ {
+
+		next := p.lines[p.pos]
+
+		if strings.TrimSpace(next.raw) == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		if next.indent <= l.indent {
+			break
+		}
+
+		if indent < 0 {
+			indent = next.indent
+		}
+
+		if next.indent < indent {
+			return nil, p.errorf(next, "bad indentation in a block scalar")
+		}
+
+		lines = append(lines, next.raw[indent:])
+	}
+
+	// Trailing blank lines belong to whatever follows, unless
+	// they're kept by the chomping indicator
+	trailing := 0
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var s string
+
+	if folded {
+		for i, line := range lines {
+			switch {
+			case i == 0 || lines[i-1] == "":
+			case line == "":
+				s += "\n"
+			default:
+				s += " "
+			}
+
+			s += line
+		}
+	} else {
+		s = strings.Join(lines, "\n")
+	}
+
+	switch chomp {
+	case "-":
+	case "+":
+		s += "\n" + strings.Repeat("\n", trailing)
+	default:
+		if len(lines) > 0 {
+			s += "\n"
+		}
+	}
+
+	return s, nil
+}
+
+// Parse a scalar or a single-line flow collection
+func (p *yamlParser) parseScalar(l yamlLine, s string) (interface{}, error) {
+
+	switch s[0] {
+	case '"':
+		if len(s) < 2 || s[len(s)-1] != '"' {
+			return nil, p.errorf(l, "unterminated string %s", s)
+		}
+
+		v, err := strconv.Unquote(s)
+
+		if err != nil {
+			return nil, p.errorf(l, "bad string %s", s)
+		}
+
+		return v, nil
+	case '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return nil, p.errorf(l, "unterminated string %s", s)
+		}
+
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	case '[':
+		if s[len(s)-1] != ']' {
+			return nil, p.errorf(l, "unterminated flow sequence (flow collections must be on one line)")
+		}
+
+		seq := make([]interface{}, 0)
+
+		for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+
+			v, err := p.parseScalar(l, item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			seq = append(seq, v)
+		}
+
+		return seq, nil
+	case '{':
+		if s[len(s)-1] != '}' {
+			return nil, p.errorf(l, "unterminated flow mapping (flow collections must be on one line)")
+		}
+
+		m := make(map[string]interface{})
+
+		for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+
+			i := yamlMappingKey(item)
+
+			if i < 0 {
+				return nil, p.errorf(l, "expected a mapping entry in %s", s)
+			}
+
+			key, err := p.parseKey(l, strings.TrimSpace(item[:i]))
+
+			if err != nil {
+				return nil, err
+			}
+
+			rest := strings.TrimSpace(item[i+1:])
+
+			if rest == "" {
+				m[key] = nil
+				continue
+			}
+
+			if m[key], err = p.parseScalar(l, rest); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	}
+
+	return plainYAMLScalar(s), nil
+}
+
+// Split the contents of a flow collection on top-level commas
+func splitYAMLFlow(s string) []string {
+
+	items := make([]string, 0)
+	depth, start := 0, 0
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '[' || s[i] == '{':
+			depth++
+		case s[i] == ']' || s[i] == '}':
+			depth--
+		case s[i] == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+
+	items = append(items, s[start:])
+
+	// Ignore empty items (including a trailing comma)
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// Interpret an unquoted scalar
+func plainYAMLScalar(s string) interface{} {
+
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return i
+		}
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+
+	return s
+}
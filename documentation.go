@@ -70,6 +70,47 @@
 // which essentially means finding values for all of the dependency requirements by type. The Port field of the ServerConfig struct requires
 // and int, and the graph has one, so it's assigned; the Host field requires as string, and that can be assigned from the graph too.
 //
+// Fields that aren't exported are ignored by default, even if they have an inj tag. A field can opt in to injection by
+// adding the unexported flag to its tag:
+//
+//  type Server struct {
+//      log Logger `inj:",unexported"`
+//  }
+//
+// There are some caveats. Unexported fields are set using package unsafe, which bypasses the visibility rules of the
+// language; the owning struct must be provided as a pointer, otherwise the field isn't addressable and the graph will
+// report an error; and fields that are inside unexported, untagged struct fields are still never found.
+//
+// Values read from datasources can be checked with validation rules in the tag: min= and max= (for numbers, durations,
+// and the lengths of strings, slices and maps), oneof= (with options separated by |), regexp= and nonzero. A value that
+// breaks a rule isn't assigned, and the failure is reported by Assert() along with the struct path of the field. Since
+// tag values are separated by commas, a regexp= rule takes up the rest of the tag (so it can contain commas, but must
+// come last). Unknown and malformed rules are also reported by Assert(), rather than being mistaken for paths:
+//
+//  type ServerConfig struct {
+//      Port  int    `inj:"server.port,min=1,max=65535"`
+//      Level string `inj:"log.level,oneof=debug|info|warn"`
+//      Code  string `inj:"country.code,nonzero,regexp=^[A-Z]{2,3}$"`
+//  }
+//
+// Any node in the graph that implements Validator has its Validate() method called after the graph is wired up.
+//
+// Groups of values and datasources that are shared between applications (for logging, metrics and so on) can be
+// bundled into a Module, and installed into a graph with Install(). Modules can require other modules, and errors about
+// a node's dependencies name the module that contributed it.
+//
+// Values can be wrapped as they're taken from the graph, without changing the code that provides them, by registering a
+// decorator with Decorate(). Decorators are functions from a type to the same type, and any other arguments they have are
+// assigned from the graph:
+//
+//  inj.Decorate(func(s Store, m Metrics) Store {
+//      return &instrumentedStore{s, m}
+//  })
+//
+// To see what a graph is doing, register an Observer with AddObserver(). It's told about every value that's provided,
+// every dependency that's assigned (and where its value came from) or left unmet, and every call to Inject(). The
+// Observer in package github.com/yourheropaul/inj/slogobserver logs all of that with package log/slog.
+//
 // Obviously these examples are trivial in the extreme, and you'd probably never use the inj package in that way. The easiest way to understand
 // the package for real-world applications is to refer to the example application: https://github.com/yourheropaul/inj/tree/master/example.
 //
@@ -0,0 +1,85 @@
+package inj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A FieldSpec declares a dependency on a struct field from outside the
+// struct, for use with Configure(). It's the programmatic equivalent of an
+// inj struct tag. Create one with Field().
+type FieldSpec struct {
+	name string
+	dep  graphNodeDependency
+}
+
+// A FieldOption modifies a FieldSpec in the same way that the values in an
+// inj struct tag modify a dependency.
+type FieldOption func(*graphNodeDependency)
+
+// Declare a dependency on a struct field, identified by name. Nested fields
+// can be specified using dots (for example "Config.Timeout"). Without any
+// options, the field is equivalent to one tagged with `inj:""`.
+func Field(name string, opts ...FieldOption) FieldSpec {
+
+	f := FieldSpec{name: name}
+
+	for _, opt := range opts {
+		opt(&f.dep)
+	}
+
+	return f
+}
+
+// Set the datasource paths for a field, equivalent to `inj:"path1,path2"`.
+func From(paths ...string) FieldOption {
+	return func(d *graphNodeDependency) {
+		d.DatasourcePaths = append(d.DatasourcePaths, paths...)
+	}
+}
+
+// Allow an unexported field to be set, equivalent to `inj:",unexported"`.
+func Unexported() FieldOption {
+	return func(d *graphNodeDependency) {
+		d.Unexported = true
+	}
+}
+
+// Mark the field as secret, equivalent to `inj:",secret"`.
+func Sensitive() FieldOption {
+	return func(d *graphNodeDependency) {
+		d.Secret = true
+	}
+}
+
+// Turn the field spec into a dependency for a given struct type
+func (f FieldSpec) dependency(t reflect.Type) (graphNodeDependency, error) {
+
+	dep := f.dep
+	dep.Path = emptyStructPath()
+
+	for _, name := range strings.Split(f.name, ".") {
+
+		if t.Kind() != reflect.Struct {
+			return dep, fmt.Errorf("Can't find field %s in %s: %s isn't a struct", f.name, t, dep.Path)
+		}
+
+		sf, exists := t.FieldByName(name)
+
+		if !exists {
+			return dep, fmt.Errorf("Can't find field %s in %s", f.name, t)
+		}
+
+		if sf.PkgPath != "" && !dep.Unexported {
+			return dep, fmt.Errorf("Field %s in %s is unexported", f.name, t)
+		}
+
+		dep.Path = dep.Path.Branch(name)
+		t = sf.Type
+	}
+
+	dep.Type = t
+
+	return dep, nil
+}
@@ -1,17 +1,39 @@
 package inj
 
-import "reflect"
+import (
+	"reflect"
+	"sync"
+	"time"
+)
 
 // A Graph object represents an flat tree of application
 // dependencies, a count of currently unmet dependencies,
 // and a list of encountered errors.
 type graph struct {
+	mu                sync.Mutex
 	nodes             nodeMap
 	unmetDependency   int
 	errors            []string
+	unmet             map[edgeKey]string
+	invalid           map[reflect.Type]string
+	datasourceErrors  []error
 	indexes           []reflect.Type
 	datasourceReaders []DatasourceReader
 	datasourceWriters []DatasourceWriter
+	methods           map[reflect.Type][]string
+	configured        map[reflect.Type][]graphNodeDependency
+	converters        []converter
+	readTimeout       time.Duration
+	lossy             bool
+	prefetched        []map[string]interface{}
+	written           map[writtenKey]interface{}
+	writeErrors       map[writtenKey]string
+	edges             map[edgeKey]edge
+	modules           map[string]*Module
+	decorators        []reflect.Value
+	decorated         map[decoratedKey]reflect.Value
+	decorating        map[decoratedKey]bool
+	observers         []Observer
 }
 
 // Create a new instance of a graph with allocated memory
@@ -21,8 +43,18 @@ func NewGraph(providers ...interface{}) Grapher {
 
 	g.nodes = make(nodeMap)
 	g.errors = make([]string, 0)
+	g.unmet = make(map[edgeKey]string)
+	g.invalid = make(map[reflect.Type]string)
 	g.datasourceReaders = make([]DatasourceReader, 0)
 	g.datasourceWriters = make([]DatasourceWriter, 0)
+	g.methods = make(map[reflect.Type][]string)
+	g.configured = make(map[reflect.Type][]graphNodeDependency)
+	g.written = make(map[writtenKey]interface{})
+	g.writeErrors = make(map[writtenKey]string)
+	g.edges = make(map[edgeKey]edge)
+	g.modules = make(map[string]*Module)
+	g.decorated = make(map[decoratedKey]reflect.Value)
+	g.decorating = make(map[decoratedKey]bool)
 
 	g.Provide(providers...)
 
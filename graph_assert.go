@@ -6,11 +6,18 @@ package inj
 // Assert() can be called any number of times.
 func (g *graph) Assert() (valid bool, errors []string) {
 
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	valid = true
 
 	if g.unmetDependency > 0 || len(g.errors) > 0 {
 		valid = false
 	}
 
-	return valid, g.errors
+	// Return a copy, since the graph may be reconnected concurrently
+	errors = make([]string, len(g.errors))
+	copy(errors, g.errors)
+
+	return valid, errors
 }
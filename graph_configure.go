@@ -0,0 +1,83 @@
+package inj
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Declare dependencies for a struct type from outside the struct, for types
+// that can't have inj struct tags (such as those from third party packages).
+// The object is only used to identify the type; it isn't added to the graph.
+//
+//  g.Configure(&http.Client{},
+//      inj.Field("Transport"),
+//      inj.Field("Timeout", inj.From("http.timeout")),
+//  )
+//
+// The declared fields behave exactly like tagged ones, and replace any tagged
+// field with the same path. Nodes of the type that are already in the graph are
+// updated, and the graph is reconnected.
+func (g *graph) Configure(obj interface{}, fields ...FieldSpec) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if obj == nil {
+		return fmt.Errorf("Can't configure a nil value")
+	}
+
+	_, stype := getReflectionTypes(obj)
+
+	if stype.Kind() != reflect.Struct {
+		return fmt.Errorf("Can't configure %s: not a struct", stype)
+	}
+
+	for _, f := range fields {
+
+		dep, err := f.dependency(stype)
+
+		if err != nil {
+			return err
+		}
+
+		g.configured[stype] = mergeDependencies(g.configured[stype], dep)
+	}
+
+	// Update existing nodes
+	for _, n := range g.nodes {
+		if _, t := getReflectionTypes(n.Object); t == stype {
+			n.Dependencies = g.dependencies(stype)
+		}
+	}
+
+	return g.provide()
+}
+
+// Find all of the dependencies for a struct type, both from
+// its tags and from any external configuration
+func (g *graph) dependencies(stype reflect.Type) []graphNodeDependency {
+
+	deps := make([]graphNodeDependency, 0)
+
+	var basePath = emptyStructPath()
+	findDependencies(stype, &deps, &basePath)
+
+	for _, dep := range g.configured[stype] {
+		deps = mergeDependencies(deps, dep)
+	}
+
+	return deps
+}
+
+// Add a dependency to a list, replacing any with the same path
+func mergeDependencies(deps []graphNodeDependency, dep graphNodeDependency) []graphNodeDependency {
+
+	for i, d := range deps {
+		if d.Path == dep.Path {
+			deps[i] = dep
+			return deps
+		}
+	}
+
+	return append(deps, dep)
+}
@@ -0,0 +1,113 @@
+package inj
+
+import (
+	"reflect"
+	"testing"
+)
+
+//////////////////////////////////////////
+// A type without any tags
+//////////////////////////////////////////
+
+type untaggedType struct {
+	Hello   InterfaceOne
+	Nested  untaggedNestedType
+	String  string
+	private InterfaceTwo
+}
+
+type untaggedNestedType struct {
+	Goodbye InterfaceTwo
+}
+
+//////////////////////////////////////////
+// Unit tests
+//////////////////////////////////////////
+
+// Field specs should produce the same dependencies as tags
+func Test_FieldSpecDependency(t *testing.T) {
+
+	typ := reflect.TypeOf(untaggedType{})
+
+	d, err := Field("Nested.Goodbye", From("one", "two")).dependency(typ)
+
+	if err != nil {
+		t.Fatalf("dependency: %s", err)
+	}
+
+	compareGraphNodeDeps(graphNodeDependency{
+		Path: ".Nested.Goodbye",
+		Type: reflect.TypeOf((*InterfaceTwo)(nil)).Elem(),
+	}, d, t)
+
+	if !reflect.DeepEqual(d.DatasourcePaths, []string{"one", "two"}) {
+		t.Errorf("Unexpected datasource paths %v", d.DatasourcePaths)
+	}
+
+	for _, f := range []FieldSpec{
+		Field("Missing"),
+		Field("String.Missing"),
+		Field("private"),
+	} {
+		if _, err := f.dependency(typ); err == nil {
+			t.Errorf("Field %s didn't error", f.name)
+		}
+	}
+
+	if _, err := Field("private", Unexported()).dependency(typ); err != nil {
+		t.Errorf("Unexported field errored: %s", err)
+	}
+}
+
+// Configured fields should be assigned, both for existing
+// and subsequently provided nodes
+func Test_ConfigureHappyPath(t *testing.T) {
+
+	g, u1, u2 := newGraph(), &untaggedType{}, &untaggedType{}
+
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"untagged.string": DEFAULT_STRING,
+	}))
+
+	g.Provide(u1, &helloSayer{}, &goodbyeSayer{})
+
+	if e := g.Configure(untaggedType{},
+		Field("Hello"),
+		Field("Nested.Goodbye"),
+		Field("String", From("untagged.string")),
+		Field("private", Unexported()),
+	); e != nil {
+		t.Fatalf("Configure: %s", e)
+	}
+
+	g.Provide(u2)
+
+	assertNoGraphErrors(t, g)
+
+	for _, u := range []*untaggedType{u1, u2} {
+
+		assertPasserInterfaceValues(u.Hello, u.Nested.Goodbye, t)
+
+		if u.private == nil {
+			t.Errorf("u.private wasn't assigned")
+		}
+
+		if g, e := u.String, DEFAULT_STRING; g != e {
+			t.Errorf("Expected '%s', got '%s'", e, g)
+		}
+	}
+}
+
+// Configuring a non-struct or a missing field should fail
+func Test_ConfigureSadPath(t *testing.T) {
+
+	g := newGraph()
+
+	if e := g.Configure("string"); e == nil {
+		t.Errorf("Configure didn't error for a non-struct")
+	}
+
+	if e := g.Configure(&untaggedType{}, Field("Missing")); e == nil {
+		t.Errorf("Configure didn't error for a missing field")
+	}
+}
@@ -1,78 +1,236 @@
 package inj
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 )
 
 // Usually called after Provide() to assign the values
-// of all requested dependencies.
-func (g *graph) connect() {
+// of all requested dependencies. Stops early if the
+// context is cancelled.
+func (g *graph) connect(ctx context.Context) {
 
 	// Reset error counts
 	g.unmetDependency = 0
 	g.errors = make([]string, 0)
+	g.unmet = make(map[edgeKey]string)
+	g.invalid = make(map[reflect.Type]string)
+	g.writeErrors = make(map[writtenKey]string)
+	g.datasourceErrors = make([]error, 0)
+	g.edges = make(map[edgeKey]edge)
+	g.decorated = make(map[decoratedKey]reflect.Value)
+
+	// Fetch every datasource path from batch readers up front
+	g.prefetch(ctx)
+	defer func() { g.prefetched = nil }()
 
 	// loop through all nodes
 	for _, node := range g.nodes {
 
+		if ctx.Err() != nil {
+			return
+		}
+
 		// assign dependencies to the object
 		for _, dep := range node.Dependencies {
-			if e := g.assignValueToNode(node.Value, dep); e != nil {
-				g.unmetDependency++
-				g.errors = append(g.errors, e.Error())
+			if e := g.assignValueToNode(ctx, node.Value, dep); e != nil {
+				e = g.addUnmet(node, dep, e)
+
+				var dserr *DatasourceError
+
+				if errors.As(e, &dserr) {
+					g.datasourceErrors = append(g.datasourceErrors, e)
+				}
 			}
 		}
+
+		// call any injection methods
+		for _, e := range g.callInjectionMethods(node) {
+			g.unmetDependency++
+			g.errors = append(g.errors, node.attribute(e).Error())
+		}
+	}
+
+	// Let nodes check themselves once everything is assigned
+	for _, node := range g.nodes {
+		g.validate(node)
+	}
+}
+
+// Let a node check itself, replacing the error from the last time it did
+func (g *graph) validate(node *graphNode) {
+
+	if message, exists := g.invalid[node.Type]; exists {
+		delete(g.invalid, node.Type)
+		g.removeError(message)
+	}
+
+	if e := validateNode(node); e != nil {
+		message := node.attribute(e).Error()
+		g.invalid[node.Type] = message
+		g.errors = append(g.errors, message)
+	}
+}
+
+// Record a dependency that couldn't be assigned, and return the error
+// (attributed to the node's module) that Assert() reports for it
+func (g *graph) addUnmet(node *graphNode, dep graphNodeDependency, e error) error {
+
+	e = node.attribute(e)
+
+	g.observeUnmet(node, dep, e)
+	g.unmetDependency++
+	g.errors = append(g.errors, e.Error())
+	g.unmet[edgeKey{node.Type, dep.Path}] = e.Error()
+
+	return e
+}
+
+// Forget the error for a dependency, if it had one, before it's assigned again
+func (g *graph) removeUnmet(node *graphNode, dep graphNodeDependency) {
+
+	key := edgeKey{node.Type, dep.Path}
+	message, exists := g.unmet[key]
+
+	if !exists {
+		return
+	}
+
+	delete(g.unmet, key)
+	g.unmetDependency--
+	g.removeError(message)
+}
+
+// Remove an error that no longer applies
+func (g *graph) removeError(message string) {
+
+	for i, e := range g.errors {
+		if e == message {
+			g.errors = append(g.errors[:i:i], g.errors[i+1:]...)
+			break
+		}
 	}
 }
 
-func (g *graph) assignValueToNode(o reflect.Value, dep graphNodeDependency) error {
+func (g *graph) assignValueToNode(ctx context.Context, o reflect.Value, dep graphNodeDependency) error {
+	return g.assignValue(ctx, o, dep, nil)
+}
+
+// Assign a value to a dependency of an object. Parameter objects, which
+// aren't in the graph, pass a function that sets a field from the extra
+// arguments to Inject() if it can; it's tried after datasources and before
+// the graph. Their dependencies aren't recorded, so they don't appear as
+// edges and aren't passed to datasource writers.
+func (g *graph) assignValue(ctx context.Context, o reflect.Value, dep graphNodeDependency, extra func(reflect.Value) bool) error {
+
+	record := extra == nil
 
 	parents := []reflect.Value{}
 	v, err := g.findFieldValue(o, dep.Path, &parents)
 	vtype := v.Type()
 
+	// Forget what met the dependency last time, until it's met again
+	key := edgeKey{o.Type(), dep.Path}
+
+	if record {
+		delete(g.edges, key)
+	}
+
 	if err != nil {
 		return err
 	}
 
+	// Dependencies with bad tags are never assigned
+	if dep.TagError != nil {
+		return fmt.Errorf("%s%s: %s", o.Type(), dep.Path, dep.TagError)
+	}
+
+	// Unexported fields can only be set if they've opted in
+	if dep.Unexported {
+		v = unexportedField(v)
+	}
+
 	// Sanity check
 	if !v.CanSet() {
-		return fmt.Errorf("%s%s can't be set", o, dep.Path)
+		return fmt.Errorf("%s%s can't be set", o.Type(), dep.Path)
 	}
 
 	// If there are any datasource paths supplied...
 	for _, path := range dep.DatasourcePaths {
 
 		// ...check to see if a datasource reader has the value
-		for _, d := range g.datasourceReaders {
+		for i, d := range g.datasourceReaders {
 
-			if dsvalue, err := d.Read(path); err == nil {
+			if dep.secret() {
+				markSecret(d, path)
+			}
 
-				typ := reflect.TypeOf(dsvalue)
+			dsvalue, err := g.read(ctx, i, path)
 
-				value := reflect.ValueOf(dsvalue)
+			// Missing keys fall through to the next reader, but
+			// any other failure is reported
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%s%s: %w", o.Type(), dep.Path, &DatasourceError{path, d, err})
+			}
 
-				if typ != vtype && typ.ConvertibleTo(vtype) {
-					value = value.Convert(vtype)
+			if err != nil {
+				continue
+			}
+
+			// Convert the value to the type of the field, if necessary
+			value, err := g.convert(reflect.ValueOf(dsvalue), vtype)
+
+			// A value that's found but can't be used is an error, not a
+			// reason to look elsewhere. Conversion errors can quote the
+			// value, so secrets get a vaguer one.
+			if err != nil {
+
+				if dep.secret() {
+					err = fmt.Errorf("Can't convert a secret value to %s", vtype)
 				}
 
-				if value.Type().AssignableTo(vtype) {
+				return fmt.Errorf("%s%s: Couldn't convert %s from datasource %T: %w%s", o.Type(), dep.Path, path, d, err, provenanceOf(d, path))
+			}
 
-					// The value can be set by reflection
-					v.Set(value)
+			// Values from datasources must satisfy any rules
+			if err := dep.validate(value); err != nil {
+				return fmt.Errorf("%s%s: %s%s", o.Type(), dep.Path, err, provenanceOf(d, path))
+			}
 
-					// Any datasourcewriters need to be updated
-					for _, w := range g.datasourceWriters {
-						w.Write(path, v.Interface())
-					}
+			// The value can be set by reflection
+			v.Set(value)
+			g.observeAssign(o, dep, v, "datasource "+path)
 
-					return nil
-				}
+			// Record what met the dependency, and update any datasourcewriters
+			if record {
+				g.edges[key] = edge{path: path}
+				g.write(dep, path, v)
 			}
+
+			return nil
 		}
 	}
 
+	// The extra arguments to Inject() come before the graph
+	if extra != nil && extra(v) {
+		return nil
+	}
+
+	// Some dependencies can only be met by datasources, and
+	// can be left alone if they're not
+	if dep.DatasourceOnly {
+
+		// ...as long as their current values satisfy any rules
+		if err := dep.validate(v); err != nil {
+			return fmt.Errorf("%s%s: %s", o.Type(), dep.Path, err)
+		}
+
+		return nil
+	}
+
 	// Run through the graph and see if anything is settable
 	for typ, node := range g.nodes {
 
@@ -93,13 +251,24 @@ func (g *graph) assignValueToNode(o reflect.Value, dep graphNodeDependency) erro
 
 		if typ.AssignableTo(v.Type()) {
 
+			// Decorators for the field's type wrap the value first
+			value, err := g.decorate(node, v.Type())
+
+			if err != nil {
+				return fmt.Errorf("%s%s: %s", o.Type(), dep.Path, err)
+			}
+
 			// The value can be set by reflection
-			v.Set(node.Value)
+			v.Set(value)
+			g.observeAssign(o, dep, v, typ.String())
 
-			// Any datasourcewriters need to be updated
-			for _, path := range dep.DatasourcePaths {
-				for _, w := range g.datasourceWriters {
-					w.Write(path, v.Interface())
+			// Record what met the dependency, and update any datasourcewriters
+			if record {
+
+				g.edges[key] = edge{provider: typ}
+
+				for _, path := range dep.DatasourcePaths {
+					g.write(dep, path, v)
 				}
 			}
 
@@ -110,6 +279,79 @@ func (g *graph) assignValueToNode(o reflect.Value, dep graphNodeDependency) erro
 	return fmt.Errorf("Couldn't find suitable dependency for %s", dep.Type)
 }
 
+// Read every datasource path in the graph from each BatchDatasourceReader in a
+// single call. A reader whose ReadMany() fails is read one path at a time
+// instead, so that the failure is reported against the affected fields. Batch
+// reads are subject to the graph's read timeout, like single reads.
+func (g *graph) prefetch(ctx context.Context) {
+
+	g.prefetched = make([]map[string]interface{}, len(g.datasourceReaders))
+
+	paths := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, node := range g.nodes {
+		for _, dep := range node.Dependencies {
+			for _, path := range dep.DatasourcePaths {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		return
+	}
+
+	sort.Strings(paths)
+
+	for i, d := range g.datasourceReaders {
+		if b, ok := d.(BatchDatasourceReader); ok {
+			if values, err := g.readMany(ctx, b, paths); err == nil {
+				g.prefetched[i] = values
+			}
+		}
+	}
+}
+
+// Read many paths from a batch reader, applying the graph's read timeout
+func (g *graph) readMany(ctx context.Context, b BatchDatasourceReader, paths []string) (map[string]interface{}, error) {
+
+	if g.readTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.readTimeout)
+		defer cancel()
+	}
+
+	return readManyContext(ctx, b, paths)
+}
+
+// Read a datasource path from the reader at an index, using any prefetched
+// values, and applying the graph's read timeout
+func (g *graph) read(ctx context.Context, i int, path string) (interface{}, error) {
+
+	if i < len(g.prefetched) && g.prefetched[i] != nil {
+
+		if v, exists := g.prefetched[i][path]; exists {
+			return v, nil
+		}
+
+		return nil, newNotFoundError("Key %s not found", path)
+	}
+
+	d := g.datasourceReaders[i]
+
+	if g.readTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.readTimeout)
+		defer cancel()
+	}
+
+	return readContext(ctx, d, path)
+}
+
 // Required a struct type
 func (g *graph) findFieldValue(parent reflect.Value, path structPath, linneage *[]reflect.Value) (reflect.Value, error) {
 
@@ -132,7 +374,7 @@ func (g *graph) findFieldValue(parent reflect.Value, path structPath, linneage *
 	f := parent.FieldByName(stub)
 
 	if !f.IsValid() {
-		return f, fmt.Errorf("Can't find field %s in %s", stub, parent)
+		return f, fmt.Errorf("Can't find field %s in %s", stub, parent.Type())
 	}
 
 	// If that's the end of the path, return the value
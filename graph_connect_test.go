@@ -1,6 +1,7 @@
 package inj
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -20,6 +21,11 @@ type invalidConnectTester struct {
 	child2 *connectTesterChild2 `inj:""`
 }
 
+type unexportedConnectTester struct {
+	Child1 *connectTesterChild1 `inj:""`
+	child2 *connectTesterChild2 `inj:",unexported"`
+}
+
 type connectTesterChild1 struct {
 	Value string
 }
@@ -46,7 +52,7 @@ func Test_ConnectHappyPath(t *testing.T) {
 
 	// Provide calls connect, but call it again
 	// explicitly
-	g.connect()
+	g.connect(context.Background())
 
 	// Basic tests against injection failure
 	if p.Child1 == nil {
@@ -88,7 +94,7 @@ func Test_ConnectDepCount(t *testing.T) {
 
 	g.Provide(&p)
 
-	g.connect()
+	g.connect(context.Background())
 
 	if g, e := g.unmetDependency, 2; g != e {
 		t.Errorf("Got %d unmet deps, expected %d", g, e)
@@ -120,7 +126,7 @@ func Test_ConnectAssignmentHappyPath(t *testing.T) {
 	g.Provide(c1, c2)
 
 	for _, gnd := range gnds {
-		if err := g.assignValueToNode(v, gnd); err != nil {
+		if err := g.assignValueToNode(context.Background(), v, gnd); err != nil {
 			t.Errorf("assignValueToNode: %s", err.Error())
 		}
 	}
@@ -163,7 +169,7 @@ func Test_ConnectAssignmentNeutralPath(t *testing.T) {
 
 	// Run through and re-assign (shouldn't error)
 	for _, gnd := range gnds {
-		if err := g.assignValueToNode(v, gnd); err != nil {
+		if err := g.assignValueToNode(context.Background(), v, gnd); err != nil {
 			t.Errorf("assignValueToNode: %s", err.Error())
 		}
 	}
@@ -195,12 +201,44 @@ func Test_ConnectSadPath1(t *testing.T) {
 
 	// Run through and assign (should error)
 	for _, gnd := range gnds {
-		if err := g.assignValueToNode(v, gnd); err == nil {
+		if err := g.assignValueToNode(context.Background(), v, gnd); err == nil {
 			t.Errorf("assignValueToNode: didn't error")
 		}
 	}
 }
 
+// Unexported fields with the opt-in flag should be assigned
+func Test_ConnectUnexportedHappyPath(t *testing.T) {
+
+	g, p := newGraph(), &unexportedConnectTester{}
+	c1, c2 := newChildren()
+
+	g.Provide(p, c1, c2)
+
+	assertNoGraphErrors(t, g)
+
+	if p.Child1 != c1 {
+		t.Errorf("Child1 wasn't assigned")
+	}
+
+	if p.child2 != c2 {
+		t.Errorf("child2 wasn't assigned")
+	}
+}
+
+// Unexported fields can't be set if the owner isn't addressable
+func Test_ConnectUnexportedSadPath(t *testing.T) {
+
+	g, p := newGraph(), unexportedConnectTester{}
+	c1, c2 := newChildren()
+
+	g.Provide(p, c1, c2)
+
+	if v, _ := g.Assert(); v {
+		t.Errorf("Assert() didn't fail for a non-pointer owner")
+	}
+}
+
 // Unmet dependencies should cause an error
 func Test_ConnectSadPath2(t *testing.T) {
 
@@ -221,7 +259,7 @@ func Test_ConnectSadPath2(t *testing.T) {
 
 	// Run through and assign (should error)
 	for _, gnd := range gnds {
-		if err := g.assignValueToNode(v, gnd); err == nil {
+		if err := g.assignValueToNode(context.Background(), v, gnd); err == nil {
 			t.Errorf("assignValueToNode: didn't error")
 		}
 	}
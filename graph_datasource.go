@@ -1,10 +1,17 @@
 package inj
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // Add any number of Datasources, DatasourceReaders or DatasourceWriters
 // to the graph. Returns an error if any of the supplied arguments aren't
-// one of the accepted types.
+// one of the accepted types, or if a datasource fails when the graph is
+// re-Provided (see Provide()).
+//
+// The graph watches any WatchableDatasources for changes, and re-assigns
+// the affected fields automatically.
 //
 // Once added, the datasources will be active immediately, and the graph
 // will automatically re-Provide itself, so that any depdendencies that
@@ -12,25 +19,66 @@ import "fmt"
 //
 func (g *graph) AddDatasource(ds ...interface{}) error {
 
-	for i, d := range ds {
-		found := false
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.addDatasources(ds...); err != nil {
+		return err
+	}
+
+	return g.provide()
+}
+
+// The implementation of AddDatasource(), without re-Providing the graph.
+// Nothing is added unless every argument is valid.
+func (g *graph) addDatasources(ds ...interface{}) error {
+
+	if err := checkDatasources(ds...); err != nil {
+		return err
+	}
+
+	for _, d := range ds {
 
 		if v, ok := d.(DatasourceReader); ok {
-			found = true
 			g.datasourceReaders = append(g.datasourceReaders, v)
 		}
 
+		if v, ok := d.(WatchableDatasource); ok {
+			g.watch(v)
+		}
+
 		if v, ok := d.(DatasourceWriter); ok {
-			found = true
 			g.datasourceWriters = append(g.datasourceWriters, v)
 		}
+	}
 
-		if !found {
+	return nil
+}
+
+// Make sure that every argument is a DatasourceReader or a DatasourceWriter
+func checkDatasources(ds ...interface{}) error {
+
+	for i, d := range ds {
+
+		_, reader := d.(DatasourceReader)
+		_, writer := d.(DatasourceWriter)
+
+		if !reader && !writer {
 			return fmt.Errorf("Supplied argument %d isn't a DatasourceReader or a DatasourceWriter", i)
 		}
 	}
 
-	g.Provide()
-
 	return nil
 }
+
+// Set the maximum time the graph waits for a single datasource read. Readers
+// that implement ContextDatasourceReader have their reads cancelled; others
+// are abandoned. A read that times out is reported as a datasource failure
+// (see Provide()). A timeout of zero means reads never time out.
+func (g *graph) SetReadTimeout(timeout time.Duration) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.readTimeout = timeout
+}
@@ -0,0 +1,118 @@
+package inj
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A node that's been passed through the decorators for a type
+type decoratedKey struct {
+	node reflect.Type
+	to   reflect.Type
+}
+
+// Register a function that wraps values of a type whenever they're taken from
+// the graph, without changing where they're provided. The function's first
+// argument and first return value must have the same type; its other arguments
+// are assigned from the graph, and it may return an error as well:
+//
+//  g.Decorate(func(s Store, m Metrics) Store {
+//      return &instrumentedStore{s, m}
+//  })
+//
+// When a node is assigned to a dependency of the decorated type (or passed to
+// a function argument of that type by Inject()), it's passed through every
+// decorator for the type, in the order they were registered. The result is
+// shared by every dependency until the graph is next connected. Failures,
+// including decorators that depend on the type they decorate, are reported by
+// Assert() (or cause Inject() to panic). As with AddConverter(), the graph is
+// re-Provided once the decorator is added.
+func (g *graph) Decorate(fn interface{}) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v := reflect.ValueOf(fn)
+
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("Decorator is %s, not a function", v.Kind())
+	}
+
+	t := v.Type()
+
+	if t.NumIn() < 1 || t.IsVariadic() || t.NumOut() < 1 || t.NumOut() > 2 || t.Out(0) != t.In(0) ||
+		(t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("Decorator %s must have the signature func(T, ...) T or func(T, ...) (T, error)", t)
+	}
+
+	g.decorators = append(g.decorators, v)
+
+	// Previously assigned values need to be decorated
+	return g.provide()
+}
+
+// Pass a node through every decorator for a type, in the order that they
+// were registered
+func (g *graph) decorate(node *graphNode, to reflect.Type) (reflect.Value, error) {
+
+	key := decoratedKey{node.Type, to}
+
+	if v, exists := g.decorated[key]; exists {
+		return v, nil
+	}
+
+	if g.decorating[key] {
+		return node.Value, fmt.Errorf("Decorators for %s depend on %s themselves", to, to)
+	}
+
+	g.decorating[key] = true
+	defer delete(g.decorating, key)
+
+	v := node.Value
+
+	for _, d := range g.decorators {
+
+		dtype := d.Type()
+
+		if dtype.In(0) != to {
+			continue
+		}
+
+		args := []reflect.Value{v}
+
+		for i := 1; i < dtype.NumIn(); i++ {
+
+			arg, err := g.decoratorArg(dtype.In(i))
+
+			if err != nil {
+				return node.Value, fmt.Errorf("Decorator %s: %s", dtype, err)
+			}
+
+			args = append(args, arg)
+		}
+
+		out := d.Call(args)
+
+		if len(out) == 2 && !out[1].IsNil() {
+			return node.Value, fmt.Errorf("Decorator %s failed: %s", dtype, out[1].Interface())
+		}
+
+		v = out[0]
+	}
+
+	g.decorated[key] = v
+
+	return v, nil
+}
+
+// Find a (decorated) value in the graph for an argument of a decorator
+func (g *graph) decoratorArg(in reflect.Type) (reflect.Value, error) {
+
+	for typ, node := range g.nodes {
+		if typ.AssignableTo(in) {
+			return g.decorate(node, in)
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("Couldn't find suitable dependency for %s", in)
+}
@@ -0,0 +1,193 @@
+package inj
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+///////////////////////////////////////////////////////////////
+// Types for decorator tests
+///////////////////////////////////////////////////////////////
+
+type decoratedStore interface {
+	Get() string
+}
+
+type baseStore struct{}
+
+func (s *baseStore) Get() string { return "base" }
+
+type wrappedStore struct {
+	inner decoratedStore
+	label string
+}
+
+func (s *wrappedStore) Get() string { return s.label + "(" + s.inner.Get() + ")" }
+
+type decoratorMetrics struct {
+	Name string
+}
+
+type decoratedService struct {
+	Store decoratedStore `inj:""`
+}
+
+type decoratedSetter struct {
+	store decoratedStore
+}
+
+func (s *decoratedSetter) SetStore(store decoratedStore) { s.store = store }
+
+type decoratedOther struct {
+	Store decoratedStore `inj:""`
+	Base  *baseStore     `inj:""`
+}
+
+// Decorators should be applied in order, with their own arguments from the graph
+func Test_Decorate(t *testing.T) {
+
+	g := newGraph()
+	s, o := &decoratedService{}, &decoratedOther{}
+	calls := 0
+
+	g.Provide(&baseStore{}, &decoratorMetrics{"metrics"}, s, o)
+
+	if err := g.Decorate(func(s decoratedStore, m *decoratorMetrics) decoratedStore {
+		calls++
+		return &wrappedStore{s, m.Name}
+	}); err != nil {
+		t.Fatalf("Decorate: %s", err)
+	}
+
+	if err := g.Decorate(func(s decoratedStore) (decoratedStore, error) {
+		return &wrappedStore{s, "cache"}, nil
+	}); err != nil {
+		t.Fatalf("Decorate: %s", err)
+	}
+
+	assertNoGraphErrors(t, g)
+
+	if got := s.Store.Get(); got != "cache(metrics(base))" {
+		t.Errorf("Expected cache(metrics(base)), got %s", got)
+	}
+
+	// The decorated value is shared by every dependency
+	if s.Store != o.Store {
+		t.Errorf("Dependencies got different decorated values")
+	}
+
+	// Dependencies of other types aren't decorated
+	if o.Base == nil || o.Base.Get() != "base" {
+		t.Errorf("Undecorated dependency was changed: %v", o.Base)
+	}
+
+	calls = 0
+
+	g.Inject(func(s decoratedStore, b *baseStore) {
+		if got := s.Get(); got != "cache(metrics(base))" {
+			t.Errorf("Expected Inject() to get cache(metrics(base)), got %s", got)
+		}
+	})
+
+	if calls != 0 {
+		t.Errorf("Decorator was called again by Inject() (%d times)", calls)
+	}
+}
+
+// Injection methods should be passed decorated values too
+func Test_DecorateInjectionMethods(t *testing.T) {
+
+	g := newGraph()
+	s := &decoratedSetter{}
+
+	g.Provide(&baseStore{})
+
+	if err := g.InjectMethods(s); err != nil {
+		t.Fatalf("InjectMethods: %s", err)
+	}
+
+	if err := g.Decorate(func(s decoratedStore) decoratedStore {
+		return &wrappedStore{s, "cache"}
+	}); err != nil {
+		t.Fatalf("Decorate: %s", err)
+	}
+
+	assertNoGraphErrors(t, g)
+
+	if s.store == nil {
+		t.Fatalf("Injection method wasn't called")
+	}
+
+	if g, e := s.store.Get(), "cache(base)"; g != e {
+		t.Errorf("Expected %s, got %s", e, g)
+	}
+}
+
+// Decorators with bad signatures should be rejected
+func Test_DecorateSignatures(t *testing.T) {
+
+	g := newGraph()
+
+	for _, fn := range []interface{}{
+		"not a function",
+		func() decoratedStore { return nil },
+		func(s decoratedStore) {},
+		func(s decoratedStore) *baseStore { return nil },
+		func(s decoratedStore) (decoratedStore, string) { return nil, "" },
+		func(s decoratedStore, m ...*decoratorMetrics) decoratedStore { return nil },
+	} {
+		if err := g.Decorate(fn); err == nil {
+			t.Errorf("Expected an error for %T", fn)
+		}
+	}
+
+	if len(g.decorators) != 0 {
+		t.Errorf("Invalid decorators were registered")
+	}
+}
+
+// Decorator failures should be reported by Assert() and Inject()
+func Test_DecorateErrors(t *testing.T) {
+
+	for _, test := range []struct {
+		fn       interface{}
+		expected string
+	}{
+		{
+			func(s decoratedStore) (decoratedStore, error) { return nil, errors.New("broken") },
+			"failed: broken",
+		},
+		{
+			func(s decoratedStore, m *decoratorMetrics) decoratedStore { return s },
+			"Couldn't find suitable dependency for *inj.decoratorMetrics",
+		},
+		{
+			func(s decoratedStore, d decoratedStore) decoratedStore { return s },
+			"depend on inj.decoratedStore themselves",
+		},
+	} {
+		g := newGraph()
+		g.Provide(&baseStore{}, &decoratedService{})
+
+		if err := g.Decorate(test.fn); err != nil {
+			t.Fatalf("Decorate: %s", err)
+		}
+
+		valid, errs := g.Assert()
+
+		if valid || len(errs) != 1 || !strings.Contains(errs[0], test.expected) {
+			t.Errorf("Expected an error containing %q, got %v", test.expected, errs)
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), test.expected) {
+					t.Errorf("Expected Inject() to panic with %q, got %v", test.expected, r)
+				}
+			}()
+
+			g.Inject(func(s decoratedStore) {})
+		}()
+	}
+}
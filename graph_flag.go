@@ -0,0 +1,43 @@
+package inj
+
+import (
+	"flag"
+	"reflect"
+	"sort"
+)
+
+// Register a flag for every datasource path of every struct in the graph,
+// including fields declared with Configure(). Flags are registered in the
+// same way as by RegisterObjectFlags(), with nodes taken in order of their
+// type names, so the first node to use a path provides its default. If the
+// flag set is nil, flag.CommandLine is used.
+func (g *graph) RegisterFlags(fs *flag.FlagSet) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	nodes := make([]*graphNode, 0, len(g.nodes))
+
+	for _, node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Type.String() < nodes[j].Type.String()
+	})
+
+	for _, node := range nodes {
+
+		_, stype := getReflectionTypes(node.Object)
+
+		if stype.Kind() != reflect.Struct {
+			continue
+		}
+
+		registerFlags(fs, node.Value, stype, g.dependencies(stype))
+	}
+}
@@ -0,0 +1,35 @@
+package inj
+
+import (
+	"flag"
+	"testing"
+)
+
+// Flags should be registered for every node, including configured fields
+func Test_GraphRegisterFlags(t *testing.T) {
+
+	g := newGraph()
+
+	if e := g.Configure(untaggedType{}, Field("String", From("untagged.string"))); e != nil {
+		t.Fatalf("Configure: %s", e)
+	}
+
+	g.Provide(&untaggedType{String: "default"}, &flagDep{Port: 8080}, map[string]int{})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	g.RegisterFlags(fs)
+
+	for _, name := range []string{"untagged.string", "port", "nested.name"} {
+		if fs.Lookup(name) == nil {
+			t.Errorf("Flag %s wasn't registered", name)
+		}
+	}
+
+	if g, e := fs.Lookup("untagged.string").DefValue, "default"; g != e {
+		t.Errorf("Expected default %s, got %s", e, g)
+	}
+
+	if g, e := fs.Lookup("port").DefValue, "8080"; g != e {
+		t.Errorf("Expected default %s, got %s", e, g)
+	}
+}
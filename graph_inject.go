@@ -1,14 +1,39 @@
 package inj
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 // Given a function, call it with arguments from the graph.
 // Throws a runtime error in the form of a panic on failure.
 func (g *graph) Inject(fn interface{}, args ...interface{}) {
 
+	// Observers are told about the call once it's over
+	defer g.observeInject(fn, time.Now())
+
+	f, argv := g.injectionArgs(fn, args)
+
+	// Variadic functions are called with an explicit slice
+	if f.Type().IsVariadic() {
+		f.CallSlice(argv)
+		return
+	}
+
+	// Make the function call, with the args which should now be complete.
+	f.Call(argv)
+}
+
+// Assemble the arguments for a call to Inject(). The graph is only locked
+// while the arguments are assembled, so that the function itself can use
+// the graph.
+func (g *graph) injectionArgs(fn interface{}, args []interface{}) (reflect.Value, []reflect.Value) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	// Reflect the input
 	f := reflect.ValueOf(fn)
 
@@ -21,11 +46,6 @@ func (g *graph) Inject(fn interface{}, args ...interface{}) {
 		panic("[inj.Inject] Passed argument is not a function")
 	}
 
-	// Variadic functions aren't currently supported
-	if ftype.IsVariadic() {
-		panic("[inj.Inject] Passed function is variadic")
-	}
-
 	// Assemble extra arg types list
 	xargs := make([]reflect.Type, len(args))
 
@@ -33,22 +53,46 @@ func (g *graph) Inject(fn interface{}, args ...interface{}) {
 		xargs[i] = reflect.TypeOf(args[i])
 	}
 
+	// Keep track of extra args that have been used for fixed
+	// arguments, so they're not reused for variadic ones
+	used := make([]bool, len(args))
+
 	// Number of required incoming arguments
 	argc := ftype.NumIn()
 
+	// The variadic argument (if there is one) is handled separately
+	fixed := argc
+
+	if ftype.IsVariadic() {
+		fixed--
+	}
+
 	// Assemble a list of function arguments
 	argv := make([]reflect.Value, argc)
 
-	for i := 0; i < argc; i++ {
+	for i := 0; i < fixed; i++ {
 
 		func() {
 			// Get an incoming arg reflection type
 			in := ftype.In(i)
 
+			// Parameter objects are assembled field by field
+			if isParamObject(in) {
+				v, err := g.paramObjectValue(in, args, xargs)
+
+				if err != nil {
+					panic(fmt.Sprintf("[inj.Inject] Can't assemble arg %d [%s]: %s", i, in, err))
+				}
+
+				argv[i] = v
+				return
+			}
+
 			// Look in the additional args list for the requirement
 			for j := 0; j < len(xargs); j++ {
-				if xargs[j].AssignableTo(in) {
+				if xargs[j] != nil && xargs[j].AssignableTo(in) {
 					argv[i] = reflect.ValueOf(args[j])
+					used[j] = true
 					return
 				}
 			}
@@ -56,7 +100,7 @@ func (g *graph) Inject(fn interface{}, args ...interface{}) {
 			// Find an entry in the graph
 			for j := 0; j < len(g.indexes); j++ {
 				if g.indexes[j].AssignableTo(in) {
-					argv[i] = g.nodes[g.indexes[j]].Value
+					argv[i] = g.decoratedArg(g.nodes[g.indexes[j]], in)
 					return
 				}
 			}
@@ -66,6 +110,120 @@ func (g *graph) Inject(fn interface{}, args ...interface{}) {
 		}()
 	}
 
-	// Make the function call, with the args which should now be complete.
-	f.Call(argv)
+	if ftype.IsVariadic() {
+		argv[fixed] = g.variadicValue(ftype.In(fixed), args, xargs, used)
+	}
+
+	return f, argv
+}
+
+// Assemble the slice for the variadic argument of a function. In order of
+// preference, the slice is:
+//
+//  1. an additional argument of the slice type itself;
+//  2. a graph node of the slice type itself;
+//  3. every additional argument that's assignable to the element type
+//     (and wasn't used for a fixed argument), in order;
+//  4. every graph node that's assignable to the element type, in no
+//     particular order.
+//
+// If none of those are found, the slice is empty.
+func (g *graph) variadicValue(in reflect.Type, args []interface{}, xargs []reflect.Type, used []bool) reflect.Value {
+
+	elem := in.Elem()
+
+	// Look for a complete slice in the additional args...
+	for j := 0; j < len(xargs); j++ {
+		if !used[j] && xargs[j] != nil && xargs[j].AssignableTo(in) {
+			return reflect.ValueOf(args[j])
+		}
+	}
+
+	// ...and then in the graph
+	for j := 0; j < len(g.indexes); j++ {
+		if g.indexes[j].AssignableTo(in) {
+			return g.decoratedArg(g.nodes[g.indexes[j]], in)
+		}
+	}
+
+	slice := reflect.MakeSlice(in, 0, 0)
+
+	// Collect individual elements from the additional args...
+	for j := 0; j < len(xargs); j++ {
+		if !used[j] && xargs[j] != nil && xargs[j].AssignableTo(elem) {
+			slice = reflect.Append(slice, reflect.ValueOf(args[j]))
+		}
+	}
+
+	if slice.Len() > 0 {
+		return slice
+	}
+
+	// ...or, failing that, from the graph
+	for j := 0; j < len(g.indexes); j++ {
+		if g.indexes[j].AssignableTo(elem) {
+			slice = reflect.Append(slice, g.decoratedArg(g.nodes[g.indexes[j]], elem))
+		}
+	}
+
+	return slice
+}
+
+// Create and populate a parameter object (a struct that embeds In). Each
+// tagged (or configured) field is assigned in the same way as a struct
+// dependency in the graph, except that the additional args are tried after
+// datasources and before the graph's nodes.
+func (g *graph) paramObjectValue(in reflect.Type, args []interface{}, xargs []reflect.Type) (reflect.Value, error) {
+
+	stype := in
+
+	if stype.Kind() == reflect.Ptr {
+		stype = stype.Elem()
+	}
+
+	// Always work with a pointer, so the fields are settable
+	ptr := reflect.New(stype)
+
+	extra := func(v reflect.Value) bool {
+		return assignExtraArg(v, args, xargs)
+	}
+
+	for _, dep := range g.dependencies(stype) {
+		if err := g.assignValue(context.Background(), ptr, dep, extra); err != nil {
+			return ptr, err
+		}
+	}
+
+	if in.Kind() == reflect.Ptr {
+		return ptr, nil
+	}
+
+	return ptr.Elem(), nil
+}
+
+// Try to set a parameter object field from the additional args. Returns true
+// if the field was set.
+func assignExtraArg(v reflect.Value, args []interface{}, xargs []reflect.Type) bool {
+
+	for j := 0; j < len(xargs); j++ {
+		if xargs[j] != nil && xargs[j].AssignableTo(v.Type()) {
+			v.Set(reflect.ValueOf(args[j]))
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pass a graph node for an argument through any decorators for the
+// argument's type
+func (g *graph) decoratedArg(node *graphNode, in reflect.Type) reflect.Value {
+
+	v, err := g.decorate(node, in)
+
+	if err != nil {
+		panic(fmt.Sprintf("[inj.Inject] Can't decorate value for [%s]: %s", in, err))
+	}
+
+	return v
 }
@@ -1,6 +1,9 @@
 package inj
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 //////////////////////////////////////////
 // Standard injection testers
@@ -110,19 +113,86 @@ func Test_GraphSimpleInjectionSadPath2(t *testing.T) {
 	t.Error("Inject failed to panic with no dependency provided")
 }
 
-// Should panic if a variadic function is passed
-func Test_GraphSimpleInjectionSadPath3(t *testing.T) {
+// Variadic functions should receive a slice provided in the graph
+func Test_GraphVariadicInjectionSliceNode(t *testing.T) {
 
 	defer func() {
-		if recover() != nil {
-			// The test has succeeded
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
 		}
 	}()
 
-	g := NewGraph()
-	g.Inject(func(s ...string) {})
+	g := NewGraph([]string{"one", "two"})
+
+	g.Inject(func(s ...string) {
+		if !reflect.DeepEqual(s, []string{"one", "two"}) {
+			t.Errorf("Unexpected variadic value %v", s)
+		}
+	})
+}
+
+// Variadic functions should receive unused, assignable additional arguments
+func Test_GraphVariadicInjectionExtraArgs(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	g := NewGraph(&helloSayer{})
+
+	g.Inject(func(i1 InterfaceOne, i ...InterfaceTwo) {
+		if len(i) != 2 {
+			t.Fatalf("Expected 2 variadic values, got %d", len(i))
+		}
+	}, &goodbyeSayer{}, &helloSayer{}, &goodbyeSayer{})
+}
+
+// Variadic functions should receive every assignable graph node
+func Test_GraphVariadicInjectionGraphNodes(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	g := NewGraph(&helloSayer{}, &goodbyeSayer{}, DEFAULT_STRING)
+
+	g.Inject(func(s string, i ...interface{}) {
+		if s != DEFAULT_STRING {
+			t.Errorf("Expected '%s', got '%s'", DEFAULT_STRING, s)
+		}
+
+		if g, e := len(i), 3; g != e {
+			t.Errorf("Expected %d variadic values, got %d", e, g)
+		}
+	})
+}
+
+// Variadic functions should receive an empty slice if nothing matches
+func Test_GraphVariadicInjectionEmpty(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	called := false
+
+	NewGraph().Inject(func(s ...string) {
+		called = true
+
+		if len(s) != 0 {
+			t.Errorf("Expected an empty slice, got %v", s)
+		}
+	})
 
-	t.Error("Inject failed to panic with a variadic function argument")
+	if !called {
+		t.Errorf("Function wasn't called")
+	}
 }
 
 // Complex injection is essentially passing additional variables
@@ -170,6 +240,136 @@ func Test_GraphComplexInjectionHappyPath2(t *testing.T) {
 	}, "string two")
 }
 
+// Parameter objects should be populated from the graph and datasources
+func Test_GraphParamObjectInjection(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	g := NewGraph(&helloSayer{}, &goodbyeSayer{})
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"datasource.string": DEFAULT_STRING,
+	}))
+
+	g.Inject(func(p paramObject) {
+		assertPasserInterfaceValues(p.Hello, p.Goodbye, t)
+
+		if g, e := p.String, DEFAULT_STRING; g != e {
+			t.Errorf("Expected '%s', got '%s'", e, g)
+		}
+	})
+
+	g.Inject(func(p *paramObject, s string) {
+		assertPasserInterfaceValues(p.Hello, p.Goodbye, t)
+
+		if g, e := s, "extra"; g != e {
+			t.Errorf("Expected '%s', got '%s'", e, g)
+		}
+	}, "extra")
+}
+
+// Parameter objects should include configured fields, and shouldn't be
+// recorded in the graph or passed to datasource writers
+func Test_GraphParamObjectInjectionConfigured(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	g := newGraph()
+	writer := NewMockDatasourceWriter()
+
+	if e := g.Configure(paramObject{}, Field("Ignored", From("param.ignored"))); e != nil {
+		t.Fatalf("Configure: %s", e)
+	}
+
+	g.AddDatasource(writer, NewMockDatasourceReader(map[string]interface{}{
+		"datasource.string": DEFAULT_STRING,
+		"param.ignored":     5,
+	}))
+
+	g.Provide(&helloSayer{}, &goodbyeSayer{})
+
+	g.Inject(func(p paramObject) {
+		if g, e := p.Ignored, 5; g != e {
+			t.Errorf("Expected %d, got %d", e, g)
+		}
+	})
+
+	if len(g.edges) != 0 {
+		t.Errorf("Expected no edges, got %v", g.edges)
+	}
+
+	if len(writer.stack) != 0 {
+		t.Errorf("Expected nothing to be written, got %v", writer.stack)
+	}
+}
+
+// Parameter object fields can come from the additional args
+func Test_GraphParamObjectInjectionExtraArgs(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	NewGraph().Inject(func(p paramObject) {
+		assertPasserInterfaceValues(p.Hello, p.Goodbye, t)
+	}, &helloSayer{}, &goodbyeSayer{}, DEFAULT_STRING)
+}
+
+// Datasources should take precedence over the additional args, and the
+// additional args over the graph
+func Test_GraphParamObjectInjectionPrecedence(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s", r)
+		}
+	}()
+
+	g := NewGraph(&helloSayer{}, "from the graph")
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"datasource.string": DEFAULT_STRING,
+	}))
+
+	g.Inject(func(p paramObject) {
+		if g, e := p.String, DEFAULT_STRING; g != e {
+			t.Errorf("Expected '%s', got '%s'", e, g)
+		}
+
+		if _, ok := p.Goodbye.(*goodbyeSayer); !ok {
+			t.Errorf("Expected the additional arg, got %T", p.Goodbye)
+		}
+	}, &goodbyeSayer{}, "extra")
+
+	NewGraph(&helloSayer{}, &goodbyeSayer{}, "from the graph").Inject(func(p paramObject) {
+		if g, e := p.String, "extra"; g != e {
+			t.Errorf("Expected '%s', got '%s'", e, g)
+		}
+	}, "extra")
+}
+
+// Should panic if a parameter object field can't be met
+func Test_GraphParamObjectInjectionSadPath(t *testing.T) {
+
+	defer func() {
+		if recover() != nil {
+			// The test has succeeded
+		}
+	}()
+
+	NewGraph(&helloSayer{}).Inject(func(p paramObject) {})
+
+	t.Error("Inject failed to panic with an unmet parameter object field")
+}
+
 //////////////////////////////////////////
 // Benchmark tests
 //////////////////////////////////////////
@@ -0,0 +1,134 @@
+package inj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// The naming convention for injection methods, used when no
+// explicit method names are given to InjectMethods()
+const injectionMethodPrefix = "Set"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Insert an object into the graph and nominate some of its methods to be
+// called with arguments from the graph, as an alternative to struct field
+// tags. This is useful for types that can't be tagged, such as those from
+// third party packages. If no method names are supplied, every exported
+// method whose name starts with "Set" (and which accepts at least one
+// argument) is used.
+//
+// The methods are called each time the graph is connected, so they may
+// be called more than once and should be idempotent. If a method's last
+// return value is an error, a non-nil result is reported by Assert(), as
+// are arguments that can't be found in the graph.
+func (g *graph) InjectMethods(obj interface{}, methods ...string) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v := reflect.ValueOf(obj)
+
+	if !v.IsValid() {
+		return fmt.Errorf("Can't inject methods of a nil value")
+	}
+
+	typ := v.Type()
+
+	if len(methods) == 0 {
+		methods = conventionalInjectionMethods(typ)
+	}
+
+	for _, name := range methods {
+		if _, exists := typ.MethodByName(name); !exists {
+			return fmt.Errorf("%s has no method %s", typ, name)
+		}
+	}
+
+	g.methods[typ] = methods
+
+	return g.provide(obj)
+}
+
+// Find all of the methods of a type that match the naming convention
+func conventionalInjectionMethods(typ reflect.Type) []string {
+
+	methods := make([]string, 0)
+
+	for i := 0; i < typ.NumMethod(); i++ {
+
+		m := typ.Method(i)
+
+		// The receiver is the first argument
+		if strings.HasPrefix(m.Name, injectionMethodPrefix) && m.Type.NumIn() > 1 {
+			methods = append(methods, m.Name)
+		}
+	}
+
+	return methods
+}
+
+// Call any registered injection methods for a node
+func (g *graph) callInjectionMethods(node *graphNode) (errors []error) {
+
+	for _, name := range g.methods[node.Type] {
+		if e := g.callInjectionMethod(node, name); e != nil {
+			errors = append(errors, e)
+		}
+	}
+
+	return
+}
+
+func (g *graph) callInjectionMethod(node *graphNode, name string) error {
+
+	m := node.Value.MethodByName(name)
+	mtype := m.Type()
+
+	if mtype.IsVariadic() {
+		return fmt.Errorf("Can't call variadic injection method %s.%s", node.Type, name)
+	}
+
+	argv := make([]reflect.Value, mtype.NumIn())
+
+	for i := range argv {
+
+		in := mtype.In(i)
+		found := false
+
+		for typ, n := range g.nodes {
+
+			// Don't pass anything to itself
+			if n == node {
+				continue
+			}
+
+			if typ.AssignableTo(in) {
+
+				v, err := g.decorate(n, in)
+
+				if err != nil {
+					return fmt.Errorf("Couldn't decorate argument %d [%s] for %s.%s: %s", i, in, node.Type, name, err)
+				}
+
+				argv[i] = v
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Couldn't find suitable argument %d [%s] for %s.%s", i, in, node.Type, name)
+		}
+	}
+
+	out := m.Call(argv)
+
+	// A trailing error return value is checked
+	if n := len(out); n > 0 && mtype.Out(n-1) == errorType && !out[n-1].IsNil() {
+		return fmt.Errorf("%s.%s: %s", node.Type, name, out[n-1].Interface())
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package inj
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+//////////////////////////////////////////
+// A type that can't be tagged
+//////////////////////////////////////////
+
+type setterType struct {
+	hello   InterfaceOne
+	goodbye InterfaceTwo
+	calls   int
+}
+
+func (s *setterType) SetHello(h InterfaceOne) { s.hello = h; s.calls++ }
+
+func (s *setterType) SetGoodbye(g InterfaceTwo) error {
+
+	if g == nil {
+		return errors.New("nil goodbye")
+	}
+
+	s.goodbye = g
+
+	return nil
+}
+
+func (s *setterType) Ignored(f FuncType) {}
+
+type failingSetterType struct{}
+
+func (s *failingSetterType) SetHello(h InterfaceOne) error {
+	return errors.New("failed")
+}
+
+//////////////////////////////////////////
+// Unit tests
+//////////////////////////////////////////
+
+// Nominated methods should be called with graph values
+func Test_InjectMethodsHappyPath(t *testing.T) {
+
+	g, s := newGraph(), &setterType{}
+
+	g.Provide(&helloSayer{}, &goodbyeSayer{})
+
+	if e := g.InjectMethods(s, "SetHello", "SetGoodbye"); e != nil {
+		t.Fatalf("InjectMethods: %s", e)
+	}
+
+	assertNoGraphErrors(t, g)
+	assertPasserInterfaceValues(s.hello, s.goodbye, t)
+
+	// Methods are called again on subsequent connections
+	calls := s.calls
+	g.Provide(DEFAULT_STRING)
+
+	if s.calls <= calls {
+		t.Errorf("SetHello wasn't called on reconnection")
+	}
+}
+
+// Without method names, the naming convention should be used
+func Test_InjectMethodsConvention(t *testing.T) {
+
+	g, s := newGraph(), &setterType{}
+
+	g.Provide(&helloSayer{}, &goodbyeSayer{})
+
+	if e := g.InjectMethods(s); e != nil {
+		t.Fatalf("InjectMethods: %s", e)
+	}
+
+	if g, e := len(g.methods[reflect.TypeOf(s)]), 2; g != e {
+		t.Errorf("Expected %d methods, got %d", e, g)
+	}
+
+	assertNoGraphErrors(t, g)
+	assertPasserInterfaceValues(s.hello, s.goodbye, t)
+}
+
+// Unknown methods should be rejected
+func Test_InjectMethodsUnknownMethod(t *testing.T) {
+
+	if e := newGraph().InjectMethods(&setterType{}, "SetNothing"); e == nil {
+		t.Errorf("InjectMethods didn't error for an unknown method")
+	}
+}
+
+// Missing arguments and returned errors should be reported by Assert()
+func Test_InjectMethodsSadPath(t *testing.T) {
+
+	g := newGraph()
+
+	g.InjectMethods(&setterType{}, "SetHello")
+
+	if v, m := g.Assert(); v || len(m) != 1 {
+		t.Errorf("Expected a single error for an unmet argument, got %v", m)
+	}
+
+	g.Provide(&helloSayer{})
+	g.InjectMethods(&failingSetterType{}, "SetHello")
+
+	if v, m := g.Assert(); v || len(m) != 1 {
+		t.Errorf("Expected a single error for a failed method, got %v", m)
+	}
+}
@@ -0,0 +1,108 @@
+package inj
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Install modules into the graph, along with any modules they require
+// (recursively). Required modules are installed before the modules that
+// require them, and each module is only ever installed once, so modules that
+// are shared by several others can be required by all of them.
+//
+// Every module's datasources are added and its values provided before the
+// graph is connected, once. If any module is invalid, none are installed.
+// Returns an error if a module requires itself (directly or indirectly), if a
+// different module with the same name is already installed, or if a module
+// has an invalid datasource; otherwise, errors are returned as they are by
+// Provide().
+func (g *graph) Install(modules ...*Module) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	order := make([]*Module, 0)
+
+	for _, m := range modules {
+		if err := g.resolveModule(m, nil, &order); err != nil {
+			return err
+		}
+	}
+
+	// Check every module before changing the graph, so that nothing
+	// is installed if any of them is invalid
+	for _, m := range order {
+		if err := checkDatasources(m.datasources...); err != nil {
+			return fmt.Errorf("Module %s: %s", m.name, err)
+		}
+	}
+
+	for _, m := range order {
+
+		g.addDatasources(m.datasources...)
+		g.insert(m.name, m.providers...)
+		g.modules[m.name] = m
+	}
+
+	return g.provide()
+}
+
+// Add a module to the installation order after the modules it requires,
+// unless it's already installed or in the order
+func (g *graph) resolveModule(m *Module, chain []*Module, order *[]*Module) error {
+
+	for i, c := range chain {
+		if c == m {
+
+			names := make([]string, 0, len(chain)-i+1)
+
+			for _, c := range chain[i:] {
+				names = append(names, c.name)
+			}
+
+			return fmt.Errorf("Module %s requires itself (%s)", m.name, strings.Join(append(names, m.name), " -> "))
+		}
+	}
+
+	if installed, exists := g.modules[m.name]; exists {
+
+		if installed != m {
+			return fmt.Errorf("A different module named %s is already installed", m.name)
+		}
+
+		return nil
+	}
+
+	for _, o := range *order {
+		if o.name == m.name {
+
+			if o != m {
+				return fmt.Errorf("Two different modules are named %s", m.name)
+			}
+
+			return nil
+		}
+	}
+
+	chain = append(chain, m)
+
+	for _, r := range m.requires {
+		if err := g.resolveModule(r, chain, order); err != nil {
+			return err
+		}
+	}
+
+	*order = append(*order, m)
+
+	return nil
+}
+
+// Add the name of the module that contributed a node to an error about it
+func (n *graphNode) attribute(e error) error {
+
+	if n.Module == "" {
+		return e
+	}
+
+	return fmt.Errorf("%w (from module %s)", e, n.Module)
+}
@@ -0,0 +1,180 @@
+package inj
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+///////////////////////////////////////////////////////////////
+// Types for module tests
+///////////////////////////////////////////////////////////////
+
+type moduleLogger struct {
+	Level string `inj:"log.level"`
+}
+
+type moduleDB struct {
+	Log *moduleLogger `inj:""`
+}
+
+type moduleCache struct {
+	Log *moduleLogger `inj:""`
+	DB  *moduleDB     `inj:""`
+}
+
+type moduleMetrics struct {
+	Sink func(string) `inj:""`
+}
+
+func newModules() (logging, db, cache *Module) {
+
+	logging = NewModule("logging").
+		Provide(&moduleLogger{}).
+		AddDatasource(NewMockDatasourceReader(map[string]interface{}{"log.level": "debug"}))
+
+	db = NewModule("db").Require(logging).Provide(&moduleDB{})
+	cache = NewModule("cache").Require(logging, db).Provide(&moduleCache{})
+
+	return
+}
+
+// Modules should be installed with everything they require
+func Test_InstallModules(t *testing.T) {
+
+	g := newGraph()
+	logging, _, cache := newModules()
+
+	if err := g.Install(cache); err != nil {
+		t.Fatalf("Install: %s", err)
+	}
+
+	assertNoGraphErrors(t, g)
+
+	if valid, errs := g.Assert(); !valid {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+
+	c := g.nodes[reflect.TypeOf(&moduleCache{})].Object.(*moduleCache)
+
+	if c.Log == nil || c.DB == nil || c.DB.Log != c.Log || c.Log.Level != "debug" {
+		t.Errorf("Modules weren't wired: %+v", c)
+	}
+
+	// Shared modules are only installed once
+	if len(g.datasourceReaders) != 1 {
+		t.Errorf("Expected 1 datasource reader, got %d", len(g.datasourceReaders))
+	}
+
+	if err := g.Install(logging); err != nil || len(g.datasourceReaders) != 1 {
+		t.Errorf("Reinstalling a module changed the graph (%v)", err)
+	}
+
+	// Nodes know which module they came from
+	for _, test := range []struct {
+		node   interface{}
+		module string
+	}{
+		{&moduleLogger{}, "logging"},
+		{&moduleDB{}, "db"},
+		{&moduleCache{}, "cache"},
+	} {
+		if m := g.nodes[reflect.TypeOf(test.node)].Module; m != test.module {
+			t.Errorf("%T: expected module %s, got %s", test.node, test.module, m)
+		}
+	}
+
+	// Providing a node directly replaces its module
+	g.Provide(&moduleLogger{})
+
+	if m := g.nodes[reflect.TypeOf(&moduleLogger{})].Module; m != "" {
+		t.Errorf("Expected no module, got %s", m)
+	}
+}
+
+// Modules that can't be installed should be reported
+func Test_InstallModuleErrors(t *testing.T) {
+
+	a := NewModule("a")
+	b := NewModule("b").Require(a)
+	a.Require(b)
+
+	if err := NewGraph().Install(a); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+
+	g := NewGraph()
+	g.Install(NewModule("logging"))
+
+	if err := g.Install(NewModule("logging")); err == nil || !strings.Contains(err.Error(), "already installed") {
+		t.Errorf("Expected a name conflict, got %v", err)
+	}
+
+	if err := NewGraph().Install(NewModule("x").Require(NewModule("y")), NewModule("y")); err == nil {
+		t.Errorf("Expected a name conflict")
+	}
+
+	if err := NewGraph().Install(NewModule("bad").AddDatasource(1)); err == nil || !strings.Contains(err.Error(), "Module bad") {
+		t.Errorf("Expected a datasource error, got %v", err)
+	}
+}
+
+// A module with an invalid datasource shouldn't leave others half-installed
+func Test_InstallModulesAtomically(t *testing.T) {
+
+	logging, _, _ := newModules()
+	bad := NewModule("bad").Require(logging).AddDatasource(1)
+
+	g := newGraph()
+
+	if err := g.Install(bad); err == nil || !strings.Contains(err.Error(), "Module bad") {
+		t.Fatalf("Expected a datasource error, got %v", err)
+	}
+
+	if len(g.modules) != 0 || len(g.nodes) != 0 || len(g.datasourceReaders) != 0 {
+		t.Errorf("Expected nothing to be installed, got %d modules, %d nodes and %d readers", len(g.modules), len(g.nodes), len(g.datasourceReaders))
+	}
+
+	// The valid module can still be installed afterwards
+	if err := g.Install(logging); err != nil {
+		t.Errorf("Install: %s", err)
+	}
+
+	if len(g.datasourceReaders) != 1 {
+		t.Errorf("Expected 1 datasource reader, got %d", len(g.datasourceReaders))
+	}
+
+	if err := g.AddDatasource(NewMockDatasourceReader(nil), 1); err == nil || len(g.datasourceReaders) != 1 {
+		t.Errorf("Expected an error and no new readers, got %v and %d readers", err, len(g.datasourceReaders))
+	}
+}
+
+// Errors and manifests should name the module that contributed a node
+func Test_ModuleAttribution(t *testing.T) {
+
+	g := NewGraph()
+	g.Install(NewModule("metrics").Provide(&moduleMetrics{}))
+
+	if valid, errs := g.Assert(); valid || len(errs) != 1 || !strings.HasSuffix(errs[0], "(from module metrics)") {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	for _, n := range g.Manifest().Nodes {
+		if strings.HasSuffix(n.Type, "moduleMetrics") && n.Module != "metrics" {
+			t.Errorf("Unexpected manifest node %+v", n)
+		}
+	}
+
+	// Moving a node between modules changes the wiring
+	before := NewGraph(&moduleLogger{}).Manifest()
+	logging, _, _ := newModules()
+
+	h := NewGraph()
+	h.Install(logging)
+
+	changes := DiffManifests(before, h.Manifest())
+
+	if len(changes) == 0 || changes[0].Kind != ModuleChanged || !strings.HasSuffix(changes[0].String(), "module changed from none to logging") {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
@@ -8,6 +8,9 @@ type graphNode struct {
 	Type         reflect.Type
 	Value        reflect.Value
 	Dependencies []graphNodeDependency
+
+	// The name of the module that contributed the node, if any
+	Module string
 }
 
 type nodeMap map[reflect.Type]*graphNode
@@ -1,68 +1,239 @@
 package inj
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 )
 
+// Struct tag flags which alter the behaviour of a dependency, rather
+// than naming a datasource path
+const (
+	tagFlagUnexported = "unexported"
+	tagFlagPrefix     = "prefix"
+	tagFlagSecret     = "secret"
+)
+
+// The secondary struct tag that overrides the key of a field
+// inside a prefixed struct
+const prefixKeyTag = "injkey"
+
 type graphNodeDependency struct {
 	DatasourcePaths []string
 	Path            structPath
 	Type            reflect.Type
+	Unexported      bool
+	Prefix          bool
+	Secret          bool
+	Rules           []validationRule
+
+	// A problem with the tag (such as an unknown rule), which is
+	// reported instead of assigning the dependency
+	TagError error
+
+	// Only datasources can meet the dependency, and it's not an
+	// error if they can't
+	DatasourceOnly bool
 }
 
 func findDependencies(t reflect.Type, deps *[]graphNodeDependency, path *structPath) error {
 
 	for i := 0; i < t.NumField(); i++ {
+		findFieldDependencies(t.Field(i), deps, path)
+	}
 
-		f := t.Field(i)
+	return nil
+}
 
-		// Ignore unpexported fields, regardless of tags
-		if f.PkgPath != "" {
-			continue
-		}
+// Find the dependencies for a single struct field
+func findFieldDependencies(f reflect.StructField, deps *[]graphNodeDependency, path *structPath) {
 
-		// Get all tags
-		tag := f.Tag
+	// Get all tags
+	tag := f.Tag
 
-		// Generate a struct path branch
-		branch := path.Branch(f.Name)
+	// Ignore unpexported fields, unless they've explicitly
+	// opted in with the unexported flag
+	if f.PkgPath != "" && !parseStructTag(tag).Unexported {
+		return
+	}
 
-		// Ignore tags that don't have injection deps
-		if !strings.Contains(string(tag), "inj:") {
+	// Generate a struct path branch
+	branch := path.Branch(f.Name)
 
-			if f.Type.Kind() == reflect.Struct {
+	// Ignore tags that don't have injection deps
+	if !strings.Contains(string(tag), "inj:") {
 
-				// Recurse
-				findDependencies(f.Type, deps, &branch)
-			}
+		if f.Type.Kind() == reflect.Struct {
 
-			continue
+			// Recurse
+			findDependencies(f.Type, deps, &branch)
 		}
 
-		// Assemble everything we know about the dependency
-		dep := parseStructTag(tag)
+		return
+	}
 
-		// Add the path in the struct
-		dep.Path = branch
+	// Assemble everything we know about the dependency
+	dep := parseStructTag(tag)
 
-		// We also know the type
-		dep.Type = f.Type
+	// Prefixed structs are populated field by field
+	if dep.Prefix && f.Type.Kind() == reflect.Struct {
 
-		// Add the dependency
-		*deps = append(*deps, dep)
+		prefixes := dep.DatasourcePaths
+
+		// Without a path, the keys are used on their own
+		if len(prefixes) == 0 {
+			prefixes = []string{""}
+		}
+
+		n := len(*deps)
+		findPrefixedDependencies(f.Type, prefixes, deps, &branch)
+
+		// Every field of a secret struct is secret, and every field
+		// of a struct with a bad tag is affected by it
+		for i := n; i < len(*deps); i++ {
+
+			(*deps)[i].Secret = (*deps)[i].Secret || dep.Secret
+
+			if dep.TagError != nil {
+				(*deps)[i].TagError = dep.TagError
+			}
+		}
+
+		return
 	}
 
-	return nil
+	// Add the path in the struct
+	dep.Path = branch
+
+	// We also know the type, so the rules can be checked
+	dep.Type = f.Type
+	dep.checkRules()
+
+	// Add the dependency
+	*deps = append(*deps, dep)
 }
 
+// Parse an inj struct tag. Since a regular expression can contain commas,
+// a regexp rule takes up the rest of the tag. Tags that start with a comma
+// have no datasource paths, so anything after it that isn't a flag or a rule
+// is an error (rather than a path).
 func parseStructTag(t reflect.StructTag) (d graphNodeDependency) {
 
 	parts := strings.Split(t.Get("inj"), ",")
+	pathless := len(parts) > 1 && parts[0] == ""
+
+	for i := 0; i < len(parts); i++ {
+
+		part := parts[i]
+
+		if strings.HasPrefix(part, ruleRegexp+"=") {
+			part = strings.Join(parts[i:], ",")
+			i = len(parts)
+		}
 
-	if len(parts) > 0 && len(parts[0]) > 0 {
-		d.DatasourcePaths = parts
+		switch part {
+		case "":
+			continue
+		case tagFlagUnexported:
+			d.Unexported = true
+		case tagFlagPrefix:
+			d.Prefix = true
+		case tagFlagSecret:
+			d.Secret = true
+		default:
+			r, isRule, err := parseValidationRule(part)
+
+			switch {
+			case err != nil:
+				if d.TagError == nil {
+					d.TagError = err
+				}
+			case isRule:
+				d.Rules = append(d.Rules, r)
+			case pathless:
+				if d.TagError == nil {
+					d.TagError = fmt.Errorf("Unknown flag %s in inj tag", part)
+				}
+			default:
+				d.DatasourcePaths = append(d.DatasourcePaths, part)
+			}
+		}
 	}
 
 	return
 }
+
+// Find the dependencies for every field of a struct that's tagged with the
+// prefix flag. Each field's datasource paths are the prefixes followed by the
+// field's key, which is its lower case name unless it has an injkey tag (and
+// fields with an injkey tag of "-" are skipped). Nested structs are treated
+// as further prefixes, unless they can be parsed from a string; fields that
+// have their own inj tags are treated normally, unless the tags only contain
+// validation rules or the secret flag.
+func findPrefixedDependencies(t reflect.Type, prefixes []string, deps *[]graphNodeDependency, path *structPath) {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		branch := path.Branch(f.Name)
+
+		// Fields with their own tags are ordinary dependencies, unless the
+		// tags only contain rules or the secret flag
+		own := parseStructTag(f.Tag)
+		keyed := len(own.DatasourcePaths) == 0 && !own.Prefix && (len(own.Rules) > 0 || own.Secret || own.TagError != nil)
+
+		if strings.Contains(string(f.Tag), "inj:") && !keyed {
+			findFieldDependencies(f, deps, path)
+			continue
+		}
+
+		key := strings.ToLower(f.Name)
+
+		if k, exists := f.Tag.Lookup(prefixKeyTag); exists {
+			key = k
+		}
+
+		if key == "-" {
+			continue
+		}
+
+		paths := make([]string, len(prefixes))
+
+		for j, prefix := range prefixes {
+			if prefix == "" {
+				paths[j] = key
+			} else {
+				paths[j] = prefix + "." + key
+			}
+		}
+
+		if f.Type.Kind() == reflect.Struct && !isLeafStruct(f.Type) {
+			findPrefixedDependencies(f.Type, paths, deps, &branch)
+			continue
+		}
+
+		dep := graphNodeDependency{
+			DatasourcePaths: paths,
+			Path:            branch,
+			Type:            f.Type,
+			Secret:          own.Secret,
+			Rules:           own.Rules,
+			TagError:        own.TagError,
+			DatasourceOnly:  true,
+		}
+
+		dep.checkRules()
+		*deps = append(*deps, dep)
+	}
+}
+
+// Returns true if a struct type should be treated as a single value
+// rather than a collection of fields (because it's parsed from a string)
+func isLeafStruct(t reflect.Type) bool {
+	return t == timeType || t == urlType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
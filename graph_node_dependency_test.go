@@ -2,7 +2,9 @@ package inj
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func compareGraphNodeDeps(d1 graphNodeDependency, d2 graphNodeDependency, t *testing.T) {
@@ -83,8 +85,148 @@ func Test_ParseStructTag(t *testing.T) {
 	for _, inp := range inps {
 		d := parseStructTag(inp.tag)
 
+		if d.Unexported {
+			t.Errorf("d.Unexported is set without the flag")
+		}
+
 		if !reflect.DeepEqual(inp.expectedValues, d.DatasourcePaths) {
 			t.Errorf("inp.expectedValues != d.DatasourcePaths")
 		}
 	}
 }
+
+// The unexported flag isn't a datasource path
+func Test_ParseStructTagUnexportedFlag(t *testing.T) {
+
+	d := parseStructTag("inj:\"some.path,unexported\"")
+
+	if !d.Unexported {
+		t.Errorf("d.Unexported isn't set")
+	}
+
+	if !reflect.DeepEqual([]string{"some.path"}, d.DatasourcePaths) {
+		t.Errorf("Unexpected datasource paths: %v", d.DatasourcePaths)
+	}
+}
+
+// Anything after a leading comma that isn't a flag or a rule is an error,
+// rather than a datasource path
+func Test_ParseStructTagLeadingComma(t *testing.T) {
+
+	d := parseStructTag("inj:\",x\"")
+
+	if d.TagError == nil || d.TagError.Error() != "Unknown flag x in inj tag" {
+		t.Errorf("Unexpected tag error %v", d.TagError)
+	}
+
+	if len(d.DatasourcePaths) != 0 {
+		t.Errorf("Unexpected datasource paths: %v", d.DatasourcePaths)
+	}
+
+	d = parseStructTag("inj:\",secret,nonzero\"")
+
+	if d.TagError != nil || !d.Secret || len(d.Rules) != 1 || len(d.DatasourcePaths) != 0 {
+		t.Errorf("Unexpected dependency %+v", d)
+	}
+
+	type config struct {
+		Name string `inj:",x"`
+	}
+
+	g := newGraph()
+	g.Provide(&config{}, "a string")
+
+	if valid, errs := g.Assert(); valid || len(errs) != 1 || !strings.HasSuffix(errs[0], ".Name: Unknown flag x in inj tag") {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+}
+
+// Unexported fields should only be found with the flag
+func Test_FindDependenciesUnexported(t *testing.T) {
+
+	for _, c := range []struct {
+		typ      reflect.Type
+		expected int
+	}{
+		{reflect.TypeOf(invalidConnectTester{}), 1},
+		{reflect.TypeOf(unexportedConnectTester{}), 2},
+	} {
+		d := make([]graphNodeDependency, 0)
+		s := emptyStructPath()
+
+		findDependencies(c.typ, &d, &s)
+
+		if g, e := len(d), c.expected; g != e {
+			t.Errorf("%s: expected %d deps, got %d", c.typ, e, g)
+		}
+	}
+}
+
+// Types for prefixed dependency testing
+type prefixedConfig struct {
+	Host     string
+	Port     int
+	MaxConns int    `injkey:"max_conns"`
+	Ignored  string `injkey:"-"`
+	Timeout  time.Duration
+	TLS      struct {
+		Cert string
+	}
+	Hello InterfaceOne `inj:""`
+	Pass  string       `inj:"secrets.db"`
+	lower string
+}
+
+type HasPrefixed struct {
+	DB    prefixedConfig `inj:"db,database,prefix"`
+	Other prefixedConfig `inj:",prefix"`
+}
+
+// Prefixed structs should produce a dependency for each field
+func Test_FindPrefixedDependencies(t *testing.T) {
+
+	d := make([]graphNodeDependency, 0)
+	s := emptyStructPath()
+
+	findDependencies(reflect.TypeOf(HasPrefixed{}), &d, &s)
+
+	expected := map[structPath][]string{
+		".DB.Host":        {"db.host", "database.host"},
+		".DB.Port":        {"db.port", "database.port"},
+		".DB.MaxConns":    {"db.max_conns", "database.max_conns"},
+		".DB.Timeout":     {"db.timeout", "database.timeout"},
+		".DB.TLS.Cert":    {"db.tls.cert", "database.tls.cert"},
+		".DB.Hello":       nil,
+		".DB.Pass":        {"secrets.db"},
+		".Other.Host":     {"host"},
+		".Other.Port":     {"port"},
+		".Other.MaxConns": {"max_conns"},
+		".Other.Timeout":  {"timeout"},
+		".Other.TLS.Cert": {"tls.cert"},
+		".Other.Hello":    nil,
+		".Other.Pass":     {"secrets.db"},
+	}
+
+	if g, e := len(d), len(expected); g != e {
+		t.Errorf("Expected %d deps, got %d", e, g)
+	}
+
+	for _, dep := range d {
+
+		paths, exists := expected[dep.Path]
+
+		if !exists {
+			t.Errorf("Unexpected dependency %s", dep.Path)
+			continue
+		}
+
+		if !reflect.DeepEqual(paths, dep.DatasourcePaths) {
+			t.Errorf("%s: expected paths %v, got %v", dep.Path, paths, dep.DatasourcePaths)
+		}
+
+		// Only the untagged fields are datasource-only
+		if g, e := dep.DatasourceOnly, paths != nil && paths[0] != "secrets.db"; g != e {
+			t.Errorf("%s: expected DatasourceOnly to be %t", dep.Path, e)
+		}
+	}
+}
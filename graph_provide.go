@@ -1,6 +1,10 @@
 package inj
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+	"sort"
+)
 
 // Insert zero or more objected into the graph, and then attempt to wire up any unmet
 // dependencies in the graph.
@@ -8,34 +12,43 @@ import "reflect"
 // As explained in the main documentation (https://godoc.org/github.com/yourheropaul/inj),
 // a graph consists of what is essentially a map of types to values. If the same type is
 // provided twice with different values, the *last* value will be stored in the graph.
+//
+// Unmet dependencies are reported by Assert(), but if a DatasourceReader fails for a
+// reason other than a missing key, Provide() returns the first such error in order of
+// their messages (which wraps a *DatasourceError).
 func (g *graph) Provide(inputs ...interface{}) error {
 
-	for _, input := range inputs {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-		// Get reflection types
-		mtype, stype := getReflectionTypes(input)
+	return g.provide(inputs...)
+}
 
-		// Assign a node in the graph
-		n := g.add(mtype)
+// Provide() with a context. If the context is cancelled (or its deadline passes)
+// while datasources are being read, wiring stops and the context's error is
+// returned. Readers that implement ContextDatasourceReader receive the context.
+func (g *graph) ProvideContext(ctx context.Context, inputs ...interface{}) error {
 
-		// Populate the new node
-		n.Object = input
-		n.Type = mtype
-		n.Value = reflect.ValueOf(input)
-		n.Name = identifier(stype)
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-		// For structs, find dependencies
-		if stype.Kind() == reflect.Struct {
-			var basePath = emptyStructPath()
-			findDependencies(stype, &n.Dependencies, &basePath)
-		}
-	}
+	return g.provideContext(ctx, inputs...)
+}
+
+// The implementation of Provide(), for use when the graph is already locked
+func (g *graph) provide(inputs ...interface{}) error {
+	return g.provideContext(context.Background(), inputs...)
+}
+
+func (g *graph) provideContext(ctx context.Context, inputs ...interface{}) error {
+
+	g.insert("", inputs...)
 
 	///////////////////////////////////////////////
 	// Plug everything together
 	///////////////////////////////////////////////
 
-	g.connect()
+	g.connect(ctx)
 
 	///////////////////////////////////////////////
 	// Store a list of types for speed later on
@@ -47,5 +60,52 @@ func (g *graph) Provide(inputs ...interface{}) error {
 		g.indexes = append(g.indexes, typ)
 	}
 
+	///////////////////////////////////////////////
+	// Datasource failures are reported immediately
+	///////////////////////////////////////////////
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(g.datasourceErrors) > 0 {
+
+		// Nodes aren't connected in any particular order, so the errors are
+		// sorted to make the one that's returned predictable
+		sort.SliceStable(g.datasourceErrors, func(i, j int) bool {
+			return g.datasourceErrors[i].Error() < g.datasourceErrors[j].Error()
+		})
+
+		return g.datasourceErrors[0]
+	}
+
 	return nil
 }
+
+// Add nodes to the graph without connecting it, recording the name of the
+// module that contributed them (if any)
+func (g *graph) insert(module string, inputs ...interface{}) {
+
+	for _, input := range inputs {
+
+		// Get reflection types
+		mtype, stype := getReflectionTypes(input)
+
+		// Assign a node in the graph
+		n := g.add(mtype)
+
+		// Populate the new node
+		n.Object = input
+		n.Type = mtype
+		n.Value = reflect.ValueOf(input)
+		n.Name = identifier(stype)
+		n.Module = module
+
+		g.observeProvide(n)
+
+		// For structs, find dependencies
+		if stype.Kind() == reflect.Struct {
+			n.Dependencies = g.dependencies(stype)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package inj
+
+import "context"
+
+// Watch a datasource for changes, for as long as its channel is open
+func (g *graph) watch(d WatchableDatasource) {
+
+	c := d.Watch()
+
+	go func() {
+		for paths := range c {
+			g.reload(paths)
+		}
+	}()
+}
+
+// Re-assign the fields bound to some datasource paths, and notify
+// any affected nodes that implement the Reloader interface.
+func (g *graph) reload(changed []string) {
+
+	set := make(map[string]bool)
+
+	for _, path := range changed {
+		set[path] = true
+	}
+
+	affected := make(map[*graphNode][]string)
+
+	g.mu.Lock()
+
+	for _, node := range g.nodes {
+		for _, dep := range node.Dependencies {
+
+			matched := false
+
+			for _, path := range dep.DatasourcePaths {
+				if set[path] {
+					matched = true
+					affected[node] = appendUnique(affected[node], path)
+				}
+			}
+
+			if !matched {
+				continue
+			}
+
+			// The dependency's previous error (if any) is replaced
+			g.removeUnmet(node, dep)
+
+			if e := g.assignValueToNode(context.Background(), node.Value, dep); e != nil {
+				g.addUnmet(node, dep, e)
+			}
+		}
+	}
+
+	// Nodes check themselves again once their fields are re-assigned
+	for node := range affected {
+		g.validate(node)
+	}
+
+	g.mu.Unlock()
+
+	// Hooks are called without the lock, so they can use the graph
+	for node, paths := range affected {
+		if r, ok := node.Object.(Reloader); ok {
+			r.OnReload(paths)
+		}
+	}
+}
+
+func appendUnique(s []string, v string) []string {
+
+	for _, e := range s {
+		if e == v {
+			return s
+		}
+	}
+
+	return append(s, v)
+}
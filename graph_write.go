@@ -0,0 +1,99 @@
+package inj
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Identifies the last value passed to a writer for a path
+type writtenKey struct {
+	writer int
+	path   string
+}
+
+// Pass the value of a field to the DatasourceWriters, redacting secrets.
+// Writers are only called if the value has changed since they last
+// received it, and their errors are reported by Assert() until they next
+// write the path successfully.
+func (g *graph) write(dep graphNodeDependency, path string, v reflect.Value) {
+
+	value := v.Interface()
+
+	for i, w := range g.datasourceWriters {
+
+		key := writtenKey{i, path}
+		previous, exists := g.written[key]
+
+		if exists && reflect.DeepEqual(previous, value) {
+			continue
+		}
+
+		var err error
+
+		if cw, ok := w.(DatasourceChangeWriter); ok {
+			err = cw.WriteChange(path, redact(dep, previous), redact(dep, value))
+		} else if tw, ok := w.(typedWriter); ok {
+			err = tw.writeTyped(path, reflect.TypeOf(value), redact(dep, value))
+		} else {
+			err = w.Write(path, redact(dep, value))
+		}
+
+		// The writer's last error for the path no longer applies
+		if message, exists := g.writeErrors[key]; exists {
+			delete(g.writeErrors, key)
+			g.removeError(message)
+		}
+
+		if err != nil {
+			message := fmt.Sprintf("Datasource %T failed to write %s: %s", w, path, err)
+			g.writeErrors[key] = message
+			g.errors = append(g.errors, message)
+			continue
+		}
+
+		g.written[key] = copyValue(value)
+	}
+}
+
+// Replace the value of a secret dependency
+func redact(dep graphNodeDependency, v interface{}) interface{} {
+
+	if v == nil || !dep.secret() {
+		return v
+	}
+
+	return Redacted
+}
+
+// Copy slices and maps, so that later changes to them are noticed
+func copyValue(v interface{}) interface{} {
+
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(c, rv)
+
+		return c.Interface()
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+
+		for _, k := range rv.MapKeys() {
+			c.SetMapIndex(k, rv.MapIndex(k))
+		}
+
+		return c.Interface()
+	}
+
+	return v
+}
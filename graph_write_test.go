@@ -0,0 +1,184 @@
+package inj
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A writer that records every call
+type recordingWriter struct {
+	writes  []string
+	changes [][2]interface{}
+	fail    error
+}
+
+func (r *recordingWriter) Write(key string, value interface{}) error {
+
+	if r.fail != nil {
+		return r.fail
+	}
+
+	r.writes = append(r.writes, key)
+
+	return nil
+}
+
+// A writer that also receives previous values
+type recordingChangeWriter struct {
+	recordingWriter
+}
+
+func (r *recordingChangeWriter) WriteChange(key string, previous, value interface{}) error {
+
+	r.changes = append(r.changes, [2]interface{}{previous, value})
+
+	return r.Write(key, value)
+}
+
+type writeTester struct {
+	Port   int      `inj:"server.port"`
+	Tags   []string `inj:"tags"`
+	Secret string   `inj:"api.key,secret"`
+}
+
+// Reconnecting the graph shouldn't repeat writes
+func Test_WritesOnlyOnChange(t *testing.T) {
+
+	r := NewMockDatasourceReader(map[string]interface{}{"server.port": 80, "tags": "a,b", "api.key": "x"})
+	w := &recordingWriter{}
+
+	g := newGraph()
+	g.AddDatasource(r, w)
+	g.Provide(&writeTester{})
+	g.Provide(1)
+	g.Provide("two")
+
+	if g, e := len(w.writes), 3; g != e {
+		t.Errorf("Expected %d writes, got %d: %v", e, g, w.writes)
+	}
+
+	// Changed values are written
+	r.stack["tags"] = "a,c"
+	g.Provide()
+
+	if g, e := strings.Join(w.writes[3:], " "), "tags"; g != e {
+		t.Errorf("Expected writes of %s, got %s", e, g)
+	}
+
+	// Secret changes are written even though the writer only sees Redacted
+	r.stack["api.key"] = "y"
+	g.Provide()
+
+	if g, e := strings.Join(w.writes[4:], " "), "api.key"; g != e {
+		t.Errorf("Expected writes of %s, got %s", e, g)
+	}
+}
+
+// Write errors should be reported, and the write retried
+func Test_WriteErrors(t *testing.T) {
+
+	w := &recordingWriter{fail: errors.New("Disk full")}
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"server.port": 80, "tags": "a", "api.key": "x"}), w)
+	g.Provide(&writeTester{})
+
+	valid, errs := g.Assert()
+
+	if valid || len(errs) != 3 {
+		t.Fatalf("Expected three errors, got %v", errs)
+	}
+
+	if !strings.Contains(errs[0], "Disk full") {
+		t.Errorf("Unexpected error %s", errs[0])
+	}
+
+	w.fail = nil
+	g.Provide()
+
+	assertNoGraphErrors(t, g)
+
+	if g, e := len(w.writes), 3; g != e {
+		t.Errorf("Expected %d writes, got %d", e, g)
+	}
+}
+
+// A type whose reloads are reported on a channel
+type reloadWriteTester struct {
+	Port    int `inj:"server.port"`
+	reloads chan []string
+}
+
+func (r *reloadWriteTester) OnReload(paths []string) {
+	r.reloads <- paths
+}
+
+// Write errors during reloads should replace each other, and be cleared by
+// a successful write
+func Test_WriteErrorsOnReload(t *testing.T) {
+
+	m := NewMemoryDatasource(map[string]interface{}{"server.port": 80})
+	defer m.Close()
+
+	w := &recordingWriter{fail: errors.New("Disk full")}
+	r := &reloadWriteTester{reloads: make(chan []string, 4)}
+
+	g := newGraph()
+	g.AddDatasource(m, w)
+	g.Provide(r)
+
+	for _, port := range []int{81, 82} {
+
+		m.Set(map[string]interface{}{"server.port": port})
+		<-r.reloads
+
+		if valid, errs := g.Assert(); valid || len(errs) != 1 {
+			t.Errorf("Expected one error for %d, got %v", port, errs)
+		}
+	}
+
+	w.fail = nil
+	m.Set(map[string]interface{}{"server.port": 83})
+	<-r.reloads
+
+	assertNoGraphErrors(t, g)
+}
+
+// Change writers should receive the previous value
+func Test_DatasourceChangeWriter(t *testing.T) {
+
+	r := NewMockDatasourceReader(map[string]interface{}{"server.port": 80, "tags": "a", "api.key": "x"})
+
+	w := &recordingChangeWriter{}
+
+	g := newGraph()
+	g.AddDatasource(r, w)
+	g.Provide(&writeTester{})
+
+	r.stack["server.port"] = 8080
+	r.stack["api.key"] = "y"
+	g.Provide()
+
+	found := map[[2]interface{}]bool{}
+
+	for _, c := range w.changes {
+		if _, ok := c[1].(int); ok {
+			found[c] = true
+		}
+	}
+
+	if !found[[2]interface{}{80, 8080}] {
+		t.Errorf("Expected a change from 80 to 8080, got %v", w.changes)
+	}
+
+	if !found[[2]interface{}{nil, 80}] {
+		t.Errorf("Expected the first write to have no previous value, got %v", w.changes)
+	}
+
+	for _, c := range w.changes {
+		if c[0] == "x" || c[1] == "x" || c[1] == "y" {
+			t.Errorf("Secret leaked to the change writer: %v", c)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package inj
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// What met a dependency when the graph was last connected: either a node
+// in the graph, or a datasource path
+type edge struct {
+	provider reflect.Type
+	path     string
+}
+
+type edgeKey struct {
+	node reflect.Type
+	path structPath
+}
+
+// A Manifest is a serializable description of a graph's wiring: its nodes,
+// the fields that they depend on, and what met each dependency. Manifests
+// from two builds of an application can be compared with DiffManifests()
+// to find changes in the wiring, such as a different implementation of an
+// interface being chosen.
+type Manifest struct {
+	Nodes []ManifestNode `json:"nodes"`
+}
+
+// A node in a Manifest. Types are named with their full package paths,
+// eg. *github.com/me/app.Server. Nodes contributed by a Module have its name.
+type ManifestNode struct {
+	Type   string          `json:"type"`
+	Module string          `json:"module,omitempty"`
+	Fields []ManifestField `json:"fields,omitempty"`
+}
+
+// A dependency of a node in a Manifest. If a node in the graph met the
+// dependency, its type is the Provider; if a datasource met it, the path
+// that was read is the Datasource. Dependencies that weren't met are Unmet,
+// except for fields that can only be populated by datasources (such as the
+// fields of prefixed structs), which are left alone.
+type ManifestField struct {
+	Path       string   `json:"path"`
+	Type       string   `json:"type"`
+	Keys       []string `json:"keys,omitempty"`
+	Provider   string   `json:"provider,omitempty"`
+	Datasource string   `json:"datasource,omitempty"`
+	Unmet      bool     `json:"unmet,omitempty"`
+}
+
+// Describe the graph's current wiring. The nodes are sorted by type, and
+// their fields are in the order in which they're declared.
+func (g *graph) Manifest() *Manifest {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m := &Manifest{Nodes: make([]ManifestNode, 0, len(g.nodes))}
+
+	for typ, node := range g.nodes {
+
+		n := ManifestNode{Type: manifestTypeName(typ), Module: node.Module}
+
+		for _, dep := range node.Dependencies {
+
+			f := ManifestField{
+				Path: dep.Path.String(),
+				Type: manifestTypeName(dep.Type),
+				Keys: append([]string(nil), dep.DatasourcePaths...),
+			}
+
+			e, met := g.edges[edgeKey{typ, dep.Path}]
+
+			switch {
+			case met && e.provider != nil:
+				f.Provider = manifestTypeName(e.provider)
+			case met:
+				f.Datasource = e.path
+			default:
+				f.Unmet = !dep.DatasourceOnly
+			}
+
+			n.Fields = append(n.Fields, f)
+		}
+
+		m.Nodes = append(m.Nodes, n)
+	}
+
+	sort.Slice(m.Nodes, func(i, j int) bool {
+		return m.Nodes[i].Type < m.Nodes[j].Type
+	})
+
+	return m
+}
+
+// The name of a type in a manifest, which includes the package path of
+// named types (through any pointers)
+func manifestTypeName(t reflect.Type) string {
+
+	prefix := ""
+
+	for t.Kind() == reflect.Ptr {
+		prefix += "*"
+		t = t.Elem()
+	}
+
+	if t.Name() == "" || t.PkgPath() == "" {
+		return prefix + t.String()
+	}
+
+	return prefix + t.PkgPath() + "." + t.Name()
+}
+
+// Write a manifest as indented JSON. Implementation of the io.WriterTo
+// interface.
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+
+	b, err := json.MarshalIndent(m, "", "\t")
+
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(append(b, '\n'))
+
+	return int64(n), err
+}
+
+// Read a manifest written by WriteTo()
+func ReadManifest(r io.Reader) (*Manifest, error) {
+
+	m := &Manifest{}
+
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
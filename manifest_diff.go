@@ -0,0 +1,168 @@
+package inj
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The kinds of change between two manifests
+type ManifestChangeKind string
+
+const (
+	NodeAdded       ManifestChangeKind = "node added"
+	NodeRemoved     ManifestChangeKind = "node removed"
+	ProviderChanged ManifestChangeKind = "provider changed"
+	KeysChanged     ManifestChangeKind = "keys changed"
+	DependencyUnmet ManifestChangeKind = "dependency unmet"
+	ModuleChanged   ManifestChangeKind = "module changed"
+)
+
+// A difference between two manifests. Field is empty for changes to whole
+// nodes. For a ProviderChanged, Before and After describe what met the
+// dependency (a node's type, or "datasource" followed by a path); for a
+// KeysChanged, they're the datasource paths, separated by commas; and for a
+// ModuleChanged, they're the names of the modules that contributed the node
+// (or "none").
+type ManifestChange struct {
+	Kind   ManifestChangeKind `json:"kind"`
+	Node   string             `json:"node"`
+	Field  string             `json:"field,omitempty"`
+	Before string             `json:"before,omitempty"`
+	After  string             `json:"after,omitempty"`
+}
+
+// Implementation of the Stringer interface, eg.
+// "*app.Server.Store: provider changed from *app.MemStore to *app.RedisStore"
+func (c ManifestChange) String() string {
+
+	switch c.Kind {
+	case NodeAdded, NodeRemoved:
+		return fmt.Sprintf("%s: %s", c.Node, c.Kind)
+	case DependencyUnmet:
+		return fmt.Sprintf("%s%s: %s (was %s)", c.Node, c.Field, c.Kind, c.Before)
+	}
+
+	return fmt.Sprintf("%s%s: %s from %s to %s", c.Node, c.Field, c.Kind, c.Before, c.After)
+}
+
+// Compare the wiring described by two manifests. The changes are ordered by
+// node, as they are in the manifests, and then by field.
+//
+// New dependencies that aren't met (including those of new nodes) are
+// reported as DependencyUnmet changes, with Before set to "new". Other
+// dependencies of new nodes aren't reported individually, and neither are
+// dependencies that are no longer unmet.
+func DiffManifests(before, after *Manifest) []ManifestChange {
+
+	changes := make([]ManifestChange, 0)
+	old := make(map[string]ManifestNode)
+
+	for _, n := range before.Nodes {
+		old[n.Type] = n
+	}
+
+	seen := make(map[string]bool)
+
+	for _, n := range after.Nodes {
+
+		seen[n.Type] = true
+		o, exists := old[n.Type]
+
+		if !exists {
+
+			changes = append(changes, ManifestChange{Kind: NodeAdded, Node: n.Type})
+
+			// A new node's dependencies are all new
+			changes = append(changes, diffFields(n.Type, nil, n.Fields)...)
+
+			continue
+		}
+
+		if o.Module != n.Module {
+			changes = append(changes, ManifestChange{
+				Kind:   ModuleChanged,
+				Node:   n.Type,
+				Before: moduleName(o.Module),
+				After:  moduleName(n.Module),
+			})
+		}
+
+		changes = append(changes, diffFields(n.Type, o.Fields, n.Fields)...)
+	}
+
+	for _, n := range before.Nodes {
+		if !seen[n.Type] {
+			changes = append(changes, ManifestChange{Kind: NodeRemoved, Node: n.Type})
+		}
+	}
+
+	return changes
+}
+
+// Compare the fields of a node in two manifests
+func diffFields(node string, before, after []ManifestField) []ManifestChange {
+
+	changes := make([]ManifestChange, 0)
+	old := make(map[string]ManifestField)
+
+	for _, f := range before {
+		old[f.Path] = f
+	}
+
+	for _, f := range after {
+
+		o, exists := old[f.Path]
+
+		change := ManifestChange{Node: node, Field: f.Path}
+
+		switch {
+		case !exists:
+			if f.Unmet {
+				change.Kind, change.Before = DependencyUnmet, "new"
+				changes = append(changes, change)
+			}
+		case f.Unmet && !o.Unmet:
+			change.Kind, change.Before = DependencyUnmet, o.source()
+			changes = append(changes, change)
+		case !f.Unmet && !o.Unmet && f.source() != o.source():
+			change.Kind, change.Before, change.After = ProviderChanged, o.source(), f.source()
+			changes = append(changes, change)
+		}
+
+		if exists && strings.Join(f.Keys, ",") != strings.Join(o.Keys, ",") {
+			changes = append(changes, ManifestChange{
+				Kind:   KeysChanged,
+				Node:   node,
+				Field:  f.Path,
+				Before: strings.Join(o.Keys, ","),
+				After:  strings.Join(f.Keys, ","),
+			})
+		}
+	}
+
+	return changes
+}
+
+// Describe what met a dependency
+func (f ManifestField) source() string {
+
+	switch {
+	case f.Provider != "":
+		return f.Provider
+	case f.Datasource != "":
+		return "datasource " + f.Datasource
+	case f.Unmet:
+		return "unmet"
+	}
+
+	return "nothing"
+}
+
+func moduleName(name string) string {
+
+	if name == "" {
+		return "none"
+	}
+
+	return name
+}
@@ -0,0 +1,161 @@
+package inj
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+///////////////////////////////////////////////////////////////
+// Types for manifest tests
+///////////////////////////////////////////////////////////////
+
+type manifestStore interface {
+	Get(string) string
+}
+
+type memoryStore struct{}
+
+func (memoryStore) Get(string) string { return "" }
+
+type redisStore struct{}
+
+func (*redisStore) Get(string) string { return "" }
+
+type manifestServer struct {
+	Store  manifestStore `inj:""`
+	Port   int           `inj:"server.port"`
+	Limits struct {
+		Max int
+	} `inj:"limits,prefix"`
+}
+
+type manifestCache struct {
+	Clock func() int `inj:""`
+}
+
+// A manifest should describe what met each dependency
+func Test_Manifest(t *testing.T) {
+
+	g := NewGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"server.port": 80}))
+	g.Provide(&manifestServer{}, memoryStore{}, &manifestCache{})
+
+	m := g.Manifest()
+
+	expected := &Manifest{Nodes: []ManifestNode{
+		{Type: "*github.com/yourheropaul/inj.manifestCache", Fields: []ManifestField{
+			{Path: ".Clock", Type: "func() int", Unmet: true},
+		}},
+		{Type: "*github.com/yourheropaul/inj.manifestServer", Fields: []ManifestField{
+			{Path: ".Store", Type: "github.com/yourheropaul/inj.manifestStore", Provider: "github.com/yourheropaul/inj.memoryStore"},
+			{Path: ".Port", Type: "int", Keys: []string{"server.port"}, Datasource: "server.port"},
+			{Path: ".Limits.Max", Type: "int", Keys: []string{"limits.max"}},
+		}},
+		{Type: "github.com/yourheropaul/inj.memoryStore"},
+	}}
+
+	// The package path depends on how the tests are run
+	for i := range m.Nodes {
+		m.Nodes[i].Type = strings.Replace(m.Nodes[i].Type, manifestPkgPath(), "github.com/yourheropaul/inj", 1)
+
+		for j := range m.Nodes[i].Fields {
+			f := &m.Nodes[i].Fields[j]
+			f.Type = strings.Replace(f.Type, manifestPkgPath(), "github.com/yourheropaul/inj", 1)
+			f.Provider = strings.Replace(f.Provider, manifestPkgPath(), "github.com/yourheropaul/inj", 1)
+		}
+	}
+
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected manifest\n%+v\nexpected\n%+v", m, expected)
+	}
+
+	// Manifests should survive serialization
+	var b bytes.Buffer
+
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %s", err)
+	}
+
+	read, err := ReadManifest(&b)
+
+	if err != nil {
+		t.Fatalf("ReadManifest: %s", err)
+	}
+
+	if !reflect.DeepEqual(read, m) {
+		t.Errorf("Manifest changed after serialization:\n%+v\n%+v", read, m)
+	}
+}
+
+func manifestPkgPath() string {
+	return reflect.TypeOf(memoryStore{}).PkgPath()
+}
+
+// Changes in wiring between two graphs should be reported
+func Test_DiffManifests(t *testing.T) {
+
+	before := NewGraph(&manifestServer{}, memoryStore{}).Manifest()
+	after := NewGraph(&manifestServer{}, &redisStore{}, &manifestCache{}).Manifest()
+
+	changes := DiffManifests(before, after)
+	kinds := make([]ManifestChangeKind, len(changes))
+
+	for i, c := range changes {
+		kinds[i] = c.Kind
+	}
+
+	expected := []ManifestChangeKind{NodeAdded, DependencyUnmet, ProviderChanged, NodeAdded, NodeRemoved}
+
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, changes)
+	}
+
+	if c := changes[1]; c.Field != ".Clock" || c.Before != "new" {
+		t.Errorf("Unexpected change %s", c)
+	}
+
+	if c := changes[2]; c.Field != ".Store" || !strings.HasSuffix(c.Before, ".memoryStore") || !strings.HasSuffix(c.After, "*"+manifestPkgPath()+".redisStore") {
+		t.Errorf("Unexpected change %s", c)
+	}
+
+	// Nothing changes between identical graphs
+	if changes := DiffManifests(before, NewGraph(&manifestServer{}, memoryStore{}).Manifest()); len(changes) != 0 {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+
+	// Dependencies that are no longer met, new unmet dependencies and
+	// changed datasource keys
+	node := func(fields ...ManifestField) *Manifest {
+		return &Manifest{Nodes: []ManifestNode{{Type: "*app.Server", Fields: fields}}}
+	}
+
+	changes = DiffManifests(
+		node(ManifestField{Path: ".Store", Provider: "*app.Store"}, ManifestField{Path: ".Port", Keys: []string{"port"}}),
+		node(ManifestField{Path: ".Store", Unmet: true}, ManifestField{Path: ".Port", Keys: []string{"server.port"}}, ManifestField{Path: ".Log", Unmet: true}),
+	)
+
+	strs := make([]string, len(changes))
+
+	for i, c := range changes {
+		strs[i] = c.String()
+	}
+
+	expectedStrs := []string{
+		"*app.Server.Store: dependency unmet (was *app.Store)",
+		"*app.Server.Port: keys changed from port to server.port",
+		"*app.Server.Log: dependency unmet (was new)",
+	}
+
+	if !reflect.DeepEqual(strs, expectedStrs) {
+		t.Errorf("Expected changes %q, got %q", expectedStrs, strs)
+	}
+
+	// Unmet dependencies of new nodes are reported too
+	changes = DiffManifests(&Manifest{}, node(ManifestField{Path: ".Store", Provider: "*app.Store"}, ManifestField{Path: ".Log", Unmet: true}))
+
+	if len(changes) != 2 || changes[0].Kind != NodeAdded || changes[1].String() != "*app.Server.Log: dependency unmet (was new)" {
+		t.Errorf("Unexpected changes %q", changes)
+	}
+}
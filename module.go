@@ -0,0 +1,65 @@
+package inj
+
+// A Module is a named, reusable bundle of values and datasources, such as
+// everything a service needs for logging or for database access. Modules
+// are installed into a graph with Install(), and may require other modules,
+// which are installed first:
+//
+//  var Logging = inj.NewModule("logging").
+//      Provide(NewLogger()).
+//      AddDatasource(inj.EnvDatasource("LOG"))
+//
+//  var Database = inj.NewModule("database").
+//      Require(Logging).
+//      Provide(&DB{})
+//
+//  g.Install(Database)
+//
+// The graph remembers which module contributed each node, and names it in
+// errors about the node's dependencies and in the graph's Manifest().
+type Module struct {
+	name        string
+	providers   []interface{}
+	datasources []interface{}
+	requires    []*Module
+}
+
+// Create an empty module with a name, which must be unique within any
+// graph it's installed into
+func NewModule(name string) *Module {
+	return &Module{name: name}
+}
+
+// The module's name
+func (m *Module) Name() string {
+	return m.name
+}
+
+// Add values to the module, which are provided to the graph when the module
+// is installed. Returns the module, so that calls can be chained.
+func (m *Module) Provide(values ...interface{}) *Module {
+
+	m.providers = append(m.providers, values...)
+
+	return m
+}
+
+// Add Datasources, DatasourceReaders or DatasourceWriters to the module,
+// which are added to the graph (before the module's values are provided)
+// when the module is installed. Returns the module, so that calls can be
+// chained.
+func (m *Module) AddDatasource(ds ...interface{}) *Module {
+
+	m.datasources = append(m.datasources, ds...)
+
+	return m
+}
+
+// Declare that the module depends on other modules, which are installed
+// before it. Returns the module, so that calls can be chained.
+func (m *Module) Require(modules ...*Module) *Module {
+
+	m.requires = append(m.requires, modules...)
+
+	return m
+}
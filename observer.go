@@ -0,0 +1,133 @@
+package inj
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// An Observer is told what a graph does as it does it, for debugging and
+// metrics. Register one with AddObserver().
+//
+// OnProvide is called for every value inserted into the graph (by Provide()
+// or Install()), before the graph is connected. OnAssign is called whenever
+// a dependency is assigned, with the object that owns it, the struct path of
+// the field (eg. ".Config.Port"), the value (or Redacted, for secrets and
+// for nodes that hold secrets) and what it came from: a node's type, or "datasource" followed by a path.
+// OnUnmet is called for every dependency that couldn't be assigned, with the
+// error that Assert() reports. OnInject is called once a call to Inject()
+// returns, with the time it took (including assembling the arguments) and
+// the reason it panicked, if it did.
+//
+// All of the methods except OnInject are called while the graph is locked,
+// so they mustn't use the graph themselves.
+type Observer interface {
+	OnProvide(node interface{})
+	OnAssign(owner interface{}, path string, value interface{}, source string)
+	OnUnmet(owner interface{}, path string, err error)
+	OnInject(fn interface{}, duration time.Duration, err error)
+}
+
+// Register an Observer, which is told about everything the graph does from
+// now on. Observers are called in the order they're registered.
+func (g *graph) AddObserver(o Observer) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.observers = append(g.observers, o)
+}
+
+//////////////////////////////////////////////
+// Notifications
+//////////////////////////////////////////////
+
+func (g *graph) observeProvide(n *graphNode) {
+
+	for _, o := range g.observers {
+		o.OnProvide(n.Object)
+	}
+}
+
+func (g *graph) observeAssign(owner reflect.Value, dep graphNodeDependency, v reflect.Value, source string) {
+
+	if len(g.observers) == 0 {
+		return
+	}
+
+	value := redact(dep, v.Interface())
+
+	// Nodes from the graph can hold secrets of their own
+	if value != Redacted && g.holdsSecrets(v, make(map[reflect.Type]bool)) {
+		value = Redacted
+	}
+
+	for _, o := range g.observers {
+		o.OnAssign(owner.Interface(), string(dep.Path), value, source)
+	}
+}
+
+// Returns true if a value is (or points to) a struct with a secret
+// dependency, directly or in any of its dependencies
+func (g *graph) holdsSecrets(v reflect.Value, seen map[reflect.Type]bool) bool {
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+
+		if v.IsNil() {
+			return false
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || seen[v.Type()] {
+		return false
+	}
+
+	seen[v.Type()] = true
+
+	for _, dep := range g.dependencies(v.Type()) {
+
+		if dep.secret() {
+			return true
+		}
+
+		if f := fieldByPath(v, dep.Path); f.IsValid() && g.holdsSecrets(f, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *graph) observeUnmet(n *graphNode, dep graphNodeDependency, err error) {
+
+	for _, o := range g.observers {
+		o.OnUnmet(n.Object, string(dep.Path), err)
+	}
+}
+
+// Notify observers about a call to Inject(). A panic is reported as an
+// error, and then carried on.
+func (g *graph) observeInject(fn interface{}, start time.Time) {
+
+	r := recover()
+
+	g.mu.Lock()
+	observers := g.observers
+	g.mu.Unlock()
+
+	var err error
+
+	if r != nil {
+		err = fmt.Errorf("%v", r)
+	}
+
+	for _, o := range observers {
+		o.OnInject(fn, time.Since(start), err)
+	}
+
+	if r != nil {
+		panic(r)
+	}
+}
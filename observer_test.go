@@ -0,0 +1,125 @@
+package inj
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////
+// A recording observer
+///////////////////////////////////////////////////////////////
+
+type recordingObserver struct {
+	events []string
+}
+
+func (r *recordingObserver) OnProvide(node interface{}) {
+	r.events = append(r.events, fmt.Sprintf("provide %T", node))
+}
+
+func (r *recordingObserver) OnAssign(owner interface{}, path string, value interface{}, source string) {
+	r.events = append(r.events, fmt.Sprintf("assign %T%s = %v from %s", owner, path, value, source))
+}
+
+func (r *recordingObserver) OnUnmet(owner interface{}, path string, err error) {
+	r.events = append(r.events, fmt.Sprintf("unmet %T%s: %s", owner, path, err))
+}
+
+func (r *recordingObserver) OnInject(fn interface{}, duration time.Duration, err error) {
+	r.events = append(r.events, fmt.Sprintf("inject %T: %v", fn, err))
+}
+
+func (r *recordingObserver) has(t *testing.T, expected ...string) {
+
+	t.Helper()
+
+	for _, e := range expected {
+
+		found := false
+
+		for _, event := range r.events {
+			if event == e {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("Missing event %q in %v", e, r.events)
+		}
+	}
+}
+
+type observedConfig struct {
+	Port     int    `inj:"port"`
+	Password string `inj:"password,secret"`
+	Host     string `inj:""`
+	Missing  bool   `inj:""`
+}
+
+type observedServer struct {
+	DB *observedDB `inj:""`
+}
+
+type observedDB struct {
+	Password string `inj:"db.password,secret"`
+}
+
+// Observers should be told about provided values, assignments, unmet
+// dependencies and injections
+func Test_Observer(t *testing.T) {
+
+	g := newGraph()
+	r := &recordingObserver{}
+
+	g.AddObserver(r)
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"port": 8080, "password": "hunter2"}))
+	g.Provide(&observedConfig{}, "localhost")
+
+	r.has(t,
+		"provide *inj.observedConfig",
+		"provide string",
+		"assign *inj.observedConfig.Port = 8080 from datasource port",
+		"assign *inj.observedConfig.Password = "+Redacted+" from datasource password",
+		"assign *inj.observedConfig.Host = localhost from string",
+		"unmet *inj.observedConfig.Missing: Couldn't find suitable dependency for bool",
+	)
+
+	r.events = nil
+
+	g.Inject(func(s string) {})
+
+	func() {
+		defer func() { recover() }()
+		g.Inject(func(b bool) {})
+	}()
+
+	r.has(t,
+		"inject func(string): <nil>",
+		"inject func(bool): [inj.Inject] Can't find value for arg 0 [bool]",
+	)
+}
+
+// Secrets shouldn't reach observers inside the nodes that hold them
+func Test_ObserverRedactsNodes(t *testing.T) {
+
+	g := newGraph()
+	r := &recordingObserver{}
+
+	g.AddObserver(r)
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"db.password": "hunter2"}))
+	g.Provide(&observedServer{}, &observedDB{})
+
+	r.has(t,
+		"assign *inj.observedServer.DB = [REDACTED] from *inj.observedDB",
+		"assign *inj.observedDB.Password = [REDACTED] from datasource db.password",
+	)
+
+	for _, event := range r.events {
+		if strings.Contains(event, "hunter2") {
+			t.Errorf("An observer was told a secret: %s", event)
+		}
+	}
+}
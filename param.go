@@ -0,0 +1,49 @@
+package inj
+
+import "reflect"
+
+// In is a marker type for parameter objects. A function passed to Inject()
+// can accept a struct that embeds In in place of a long list of positional
+// arguments; the struct is created by inj, and its tagged fields are resolved
+// exactly as they would be for a struct in the graph (including datasource
+// paths):
+//
+//  type HandlerParams struct {
+//      inj.In
+//
+//      Log    Logger `inj:""`
+//      Config Config `inj:""`
+//      Port   int    `inj:"server.port"`
+//  }
+//
+//  inj.Inject(func(p HandlerParams) {
+//      p.Log("Listening on %d", p.Port)
+//  })
+//
+// The parameter can be either the struct itself or a pointer to it. Additional
+// arguments passed to Inject() take precedence over the graph, as they do for
+// ordinary arguments.
+type In struct{}
+
+var inType = reflect.TypeOf(In{})
+
+// Returns true if the type is a struct (or a pointer to a struct)
+// that embeds the In marker
+func isParamObject(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == inType {
+			return true
+		}
+	}
+
+	return false
+}
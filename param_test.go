@@ -0,0 +1,48 @@
+package inj
+
+import (
+	"reflect"
+	"testing"
+)
+
+//////////////////////////////////////////
+// Parameter object types
+//////////////////////////////////////////
+
+type paramObject struct {
+	In
+
+	Hello   InterfaceOne `inj:""`
+	Goodbye InterfaceTwo `inj:""`
+	String  string       `inj:"datasource.string"`
+
+	// Not included in the injection
+	Ignored int
+}
+
+type notParamObject struct {
+	Hello InterfaceOne `inj:""`
+}
+
+//////////////////////////////////////////
+// Unit tests
+//////////////////////////////////////////
+
+// Only structs embedding In are parameter objects
+func Test_IsParamObject(t *testing.T) {
+
+	for i, c := range []struct {
+		typ      reflect.Type
+		expected bool
+	}{
+		{reflect.TypeOf(paramObject{}), true},
+		{reflect.TypeOf(&paramObject{}), true},
+		{reflect.TypeOf(notParamObject{}), false},
+		{reflect.TypeOf(In{}), false},
+		{reflect.TypeOf(""), false},
+	} {
+		if g, e := isParamObject(c.typ), c.expected; g != e {
+			t.Errorf("[%d] Expected %t, got %t", i, e, g)
+		}
+	}
+}
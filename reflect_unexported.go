@@ -0,0 +1,21 @@
+package inj
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Get a settable version of an unexported struct field. The field must
+// be addressable (which means its owner must have been provided as a
+// pointer); if it isn't, the original value is returned unchanged.
+//
+// This bypasses the visibility rules of the language, so it's only
+// used for fields that explicitly opt in with the `unexported` flag.
+func unexportedField(v reflect.Value) reflect.Value {
+
+	if v.CanSet() || !v.CanAddr() {
+		return v
+	}
+
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
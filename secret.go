@@ -0,0 +1,89 @@
+package inj
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// The text that's used in place of a secret value
+const Redacted = "[REDACTED]"
+
+// A Secret is a string that's never printed. Formatting it with the fmt
+// package (with any verb), or marshalling it as JSON or text, produces
+// Redacted; the actual value can only be retrieved with Value() or a
+// conversion to string.
+//
+//  type DBConfig struct {
+//      Password inj.Secret `inj:"db.password"`
+//  }
+//
+// Fields of type Secret are treated as secret dependencies automatically.
+// Other types of field can be marked as secret with the secret flag:
+//
+//  type DBConfig struct {
+//      Password string `inj:"db.password,secret"`
+//  }
+//
+// The values of secret dependencies are redacted wherever inj reports them:
+// in Assert() errors, in calls to DatasourceWriters (which receive Redacted
+// instead of the value), in flag defaults registered by RegisterFlags(), and
+// in the provenance recorded by a LayeredDatasource. Note that the secret
+// flag can't stop your own code from printing a plain string field; use the
+// Secret type for that.
+type Secret string
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// Get the actual value of the secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// Implementation of the Stringer interface
+func (s Secret) String() string {
+	return Redacted
+}
+
+// Implementation of the GoStringer interface
+func (s Secret) GoString() string {
+	return Redacted
+}
+
+// Implementation of the fmt.Formatter interface, which redacts the value
+// for every verb
+func (s Secret) Format(f fmt.State, verb rune) {
+	io.WriteString(f, Redacted)
+}
+
+// Implementation of the encoding.TextMarshaler interface (which is also
+// used by encoding/json)
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+// Returns true if the value of a dependency mustn't be reported
+func (d graphNodeDependency) secret() bool {
+
+	t := d.Type
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return d.Secret || t == secretType
+}
+
+// A DatasourceReader that records values (such as a LayeredDatasource) can
+// be told which paths are secret, so that it can redact them
+type secretMarker interface {
+	markSecret(path string)
+}
+
+// Tell a reader that a path is secret, if it's interested
+func markSecret(r DatasourceReader, path string) {
+
+	if m, ok := r.(secretMarker); ok {
+		m.markSecret(path)
+	}
+}
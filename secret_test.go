@@ -0,0 +1,171 @@
+package inj
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const secretValue = "hunter2"
+
+type secretConfig struct {
+	Password string `inj:"db.password,secret"`
+	APIKey   Secret `inj:"api.key"`
+	User     string `inj:"db.user"`
+	Nested   struct {
+		Token string `injkey:"token"`
+	} `inj:"auth,prefix,secret"`
+
+	// Unmet, so that Assert() has something to say
+	Missing fmt.Stringer `inj:""`
+}
+
+// Fail if any of the outputs contain the secret
+func assertNoLeaks(t *testing.T, outputs map[string]string) {
+
+	for name, output := range outputs {
+		if strings.Contains(output, secretValue) {
+			t.Errorf("%s leaks the secret: %s", name, output)
+		}
+	}
+}
+
+// Secret values should never appear in anything inj reports
+func Test_SecretRedaction(t *testing.T) {
+
+	layered := NewLayeredDatasource().
+		Add("defaults", PriorityDefaults, NewMockDatasourceReader(map[string]interface{}{
+			"db.password": secretValue,
+			"api.key":     secretValue,
+			"db.user":     "admin",
+			"auth.token":  secretValue,
+		}))
+
+	writer := NewMockDatasourceWriter()
+	c := &secretConfig{}
+
+	g := newGraph()
+	g.AddDatasource(NewCachedDatasource(layered, 0), writer)
+	g.Provide(c)
+
+	// The values are assigned
+	if c.Password != secretValue || c.APIKey.Value() != secretValue || c.Nested.Token != secretValue {
+		t.Fatalf("Secrets weren't assigned: %#v", c)
+	}
+
+	outputs := map[string]string{
+		"%v":   fmt.Sprintf("%v", c.APIKey),
+		"%s":   fmt.Sprintf("%s", c.APIKey),
+		"%q":   fmt.Sprintf("%q", c.APIKey),
+		"%x":   fmt.Sprintf("%x", c.APIKey),
+		"%#v":  fmt.Sprintf("%#v", c.APIKey),
+		"%+v":  fmt.Sprintf("%+v", struct{ K Secret }{c.APIKey}),
+		"json": mustMarshal(t, struct{ K Secret }{c.APIKey}),
+	}
+
+	valid, errs := g.Assert()
+
+	if valid {
+		t.Errorf("Expected Assert() to fail")
+	}
+
+	outputs["Assert()"] = strings.Join(errs, "\n")
+
+	for path, v := range writer.stack {
+		outputs["Write("+path+")"] = fmt.Sprintf("%v", v)
+	}
+
+	if v := writer.stack["db.password"]; v != Redacted {
+		t.Errorf("Expected the writer to receive %s, got %v", Redacted, v)
+	}
+
+	if v := writer.stack["db.user"]; v != "admin" {
+		t.Errorf("Expected the writer to receive admin, got %v", v)
+	}
+
+	for _, p := range layered.Provenances() {
+		outputs["Provenance("+p.Path+")"] = p.String()
+		outputs["Provenance("+p.Path+") %+v"] = fmt.Sprintf("%+v", p)
+	}
+
+	if p, _ := layered.Provenance("db.user"); p.Value != "admin" {
+		t.Errorf("Expected non-secret provenance to be kept, got %v", p)
+	}
+
+	assertNoLeaks(t, outputs)
+}
+
+// Flag defaults for secret fields shouldn't appear in the usage
+func Test_SecretFlagDefaults(t *testing.T) {
+
+	c := &secretConfig{Password: secretValue, APIKey: secretValue, User: "admin"}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	if err := RegisterObjectFlags(fs, c); err != nil {
+		t.Fatalf("RegisterObjectFlags: %s", err)
+	}
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+
+	if !strings.Contains(usage.String(), "admin") {
+		t.Errorf("Expected non-secret defaults in the usage: %s", usage.String())
+	}
+
+	fs.Parse([]string{"-api.key", secretValue})
+
+	outputs := map[string]string{"usage": usage.String()}
+
+	fs.Visit(func(f *flag.Flag) {
+		outputs["-"+f.Name] = f.Value.String()
+	})
+
+	assertNoLeaks(t, outputs)
+
+	// The value itself is available to the graph
+	g := newGraph()
+	g.AddDatasource(FlagDatasource(fs))
+
+	k := &struct {
+		APIKey Secret `inj:"api.key"`
+	}{}
+
+	g.Provide(k)
+
+	if k.APIKey.Value() != secretValue {
+		t.Errorf("Expected the flag value to be assigned, got %q", string(k.APIKey))
+	}
+}
+
+// Configured fields can be marked as secret
+func Test_SensitiveField(t *testing.T) {
+
+	type external struct {
+		Password string
+	}
+
+	writer := NewMockDatasourceWriter()
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"password": secretValue}), writer)
+	g.Configure(&external{}, Field("Password", From("password"), Sensitive()))
+	g.Provide(&external{})
+
+	writer.Assert(t, "password", Redacted)
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+
+	return string(b)
+}
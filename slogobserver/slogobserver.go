@@ -0,0 +1,72 @@
+// Package slogobserver provides an inj.Observer that logs to a slog.Logger.
+// It's kept apart from package inj so that only programs that use it need
+// Go 1.21 or later.
+package slogobserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// An Observer that logs what a graph does to a slog.Logger. Provided values,
+// assignments and successful calls to Inject() are logged at debug level,
+// unmet dependencies at warn level and failed calls to Inject() at error
+// level:
+//
+//  g.AddObserver(slogobserver.New(slog.Default()))
+type Observer struct {
+	logger *slog.Logger
+}
+
+// Create an observer that logs to a slog.Logger (or slog.Default(), if the
+// logger is nil)
+func New(logger *slog.Logger) *Observer {
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Observer{logger}
+}
+
+// Implementation of the inj.Observer interface
+func (s *Observer) OnProvide(node interface{}) {
+	s.logger.Debug("inj: provided", "node", fmt.Sprintf("%T", node))
+}
+
+// Implementation of the inj.Observer interface
+func (s *Observer) OnAssign(owner interface{}, path string, value interface{}, source string) {
+	s.logger.Debug("inj: assigned",
+		"node", fmt.Sprintf("%T", owner),
+		"path", path,
+		"value", value,
+		"source", source,
+	)
+}
+
+// Implementation of the inj.Observer interface
+func (s *Observer) OnUnmet(owner interface{}, path string, err error) {
+	s.logger.Warn("inj: unmet dependency",
+		"node", fmt.Sprintf("%T", owner),
+		"path", path,
+		"error", err,
+	)
+}
+
+// Implementation of the inj.Observer interface
+func (s *Observer) OnInject(fn interface{}, duration time.Duration, err error) {
+
+	level := slog.LevelDebug
+
+	if err != nil {
+		level = slog.LevelError
+	}
+
+	s.logger.Log(context.Background(), level, "inj: injected",
+		"func", fmt.Sprintf("%T", fn),
+		"duration", duration,
+		"error", err,
+	)
+}
@@ -0,0 +1,40 @@
+package slogobserver
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/yourheropaul/inj"
+)
+
+type config struct {
+	Host    string `inj:""`
+	Missing bool   `inj:""`
+}
+
+// The observer should log events at the right levels
+func Test_Observer(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	g := inj.NewGraph()
+	g.AddObserver(New(logger))
+	g.Provide(&config{}, "localhost")
+	g.Inject(func(s string) {})
+
+	out := buf.String()
+
+	for _, expected := range []string{
+		`level=DEBUG msg="inj: provided" node=*slogobserver.config`,
+		`level=DEBUG msg="inj: assigned" node=*slogobserver.config path=.Host value=localhost source=string`,
+		`level=WARN msg="inj: unmet dependency" node=*slogobserver.config path=.Missing`,
+		`level=DEBUG msg="inj: injected" func=func(string)`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected log to contain %q, got:\n%s", expected, out)
+		}
+	}
+}
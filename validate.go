@@ -0,0 +1,235 @@
+package inj
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation rules that can be used in inj struct tags
+const (
+	ruleMin     = "min"
+	ruleMax     = "max"
+	ruleOneOf   = "oneof"
+	ruleRegexp  = "regexp"
+	ruleNonZero = "nonzero"
+)
+
+// A Validator is a node that can check itself once its dependencies have
+// been assigned. Validate() is called on every node in the graph that
+// implements it each time the graph is connected, and on each node whose
+// fields are reloaded from a WatchableDatasource; any error is reported by
+// Assert() until the node is next validated. Like injection methods, it's
+// called while the graph is busy, so it mustn't call the graph itself.
+type Validator interface {
+	Validate() error
+}
+
+// A rule that a datasource value must satisfy
+type validationRule struct {
+	name string
+	arg  string
+	re   *regexp.Regexp
+}
+
+// Parse a struct tag part as a validation rule, if it is one. Any part that
+// contains an equals sign is a rule, so misspelt rule names and invalid
+// regular expressions are errors, rather than datasource paths.
+func parseValidationRule(part string) (validationRule, bool, error) {
+
+	if part == ruleNonZero {
+		return validationRule{name: ruleNonZero}, true, nil
+	}
+
+	i := strings.Index(part, "=")
+
+	if i < 0 {
+		return validationRule{}, false, nil
+	}
+
+	r := validationRule{name: part[:i], arg: part[i+1:]}
+
+	switch r.name {
+	case ruleMin, ruleMax:
+	case ruleOneOf:
+		if r.arg == "" {
+			return r, true, fmt.Errorf("Rule %s has no options", ruleOneOf)
+		}
+	case ruleRegexp:
+		re, err := regexp.Compile(r.arg)
+
+		if err != nil {
+			return r, true, fmt.Errorf("Invalid regexp rule %s: %s", r.arg, err)
+		}
+
+		r.re = re
+	default:
+		return r, true, fmt.Errorf("Unknown rule %s", part)
+	}
+
+	return r, true, nil
+}
+
+// Check that every rule for a dependency can be applied to its type, and
+// record the first that can't
+func (d *graphNodeDependency) checkRules() {
+
+	for _, r := range d.Rules {
+
+		if d.TagError != nil {
+			return
+		}
+
+		d.TagError = r.checkType(d.Type)
+	}
+}
+
+// Check that a rule can be applied to a type
+func (r validationRule) checkType(t reflect.Type) error {
+
+	if r.name != ruleMin && r.name != ruleMax {
+		return nil
+	}
+
+	var err error
+
+	switch {
+	case t == durationType:
+		_, err = time.ParseDuration(r.arg)
+	case isNumeric(t) || t.Kind() == reflect.String || t.Kind() == reflect.Slice || t.Kind() == reflect.Map || t.Kind() == reflect.Array:
+		_, err = strconv.ParseFloat(r.arg, 64)
+	default:
+		return fmt.Errorf("Rule %s can't be applied to %s", r.name, t)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Invalid %s rule %s: %s", r.name, r.arg, err)
+	}
+
+	return nil
+}
+
+// Check a value against every rule for a dependency
+func (d graphNodeDependency) validate(v reflect.Value) error {
+
+	for _, r := range d.Rules {
+		if err := r.check(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check a value against a rule. The messages never include the value,
+// since it might be secret.
+func (r validationRule) check(v reflect.Value) error {
+
+	switch r.name {
+	case ruleNonZero:
+		if zero(v) {
+			return errors.New("Must not be empty")
+		}
+
+	case ruleMin, ruleMax:
+		return r.checkBound(v)
+
+	case ruleOneOf:
+		options := strings.Split(r.arg, "|")
+		s := valueString(v)
+
+		for _, option := range options {
+			if s == option {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Must be one of %s", strings.Join(options, ", "))
+
+	case ruleRegexp:
+		if !r.re.MatchString(valueString(v)) {
+			return fmt.Errorf("Must match %s", r.arg)
+		}
+	}
+
+	return nil
+}
+
+// Check a min or max rule. Numbers are compared by value, durations can be
+// compared with values like "1s", and strings, slices and maps are compared
+// by length.
+func (r validationRule) checkBound(v reflect.Value) error {
+
+	var got, limit float64
+	var err error
+
+	what := "Must be"
+
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(r.arg)
+		got, limit = float64(v.Int()), float64(d)
+
+	case isNumeric(v.Type()):
+		limit, err = strconv.ParseFloat(r.arg, 64)
+
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			got = v.Float()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			got = float64(v.Uint())
+		default:
+			got = float64(v.Int())
+		}
+
+	case v.Kind() == reflect.String || v.Kind() == reflect.Slice || v.Kind() == reflect.Map || v.Kind() == reflect.Array:
+		limit, err = strconv.ParseFloat(r.arg, 64)
+		got = float64(v.Len())
+		what = "Length must be"
+
+	default:
+		return fmt.Errorf("Rule %s can't be applied to %s", r.name, v.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("Invalid %s rule %s: %s", r.name, r.arg, err)
+	}
+
+	if r.name == ruleMin && got < limit {
+		return fmt.Errorf("%s at least %s", what, r.arg)
+	}
+
+	if r.name == ruleMax && got > limit {
+		return fmt.Errorf("%s at most %s", what, r.arg)
+	}
+
+	return nil
+}
+
+// The string form of a value, for matching. Strings (including secrets)
+// are used as they are.
+func valueString(v reflect.Value) string {
+
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// Call Validate() on a node, if it has it
+func validateNode(node *graphNode) error {
+
+	if v, ok := node.Object.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("%s: %s", node.Type, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package inj
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validatedConfig struct {
+	Port    int           `inj:"server.port,min=1,max=65535"`
+	Level   string        `inj:"log.level,oneof=debug|info|warn"`
+	Name    string        `inj:"app.name,regexp=^[a-z]+$"`
+	URL     string        `inj:"app.url,nonzero"`
+	Timeout time.Duration `inj:"app.timeout,max=1m"`
+	Tags    []string      `inj:"app.tags,min=1"`
+	DB      struct {
+		Host string `inj:",nonzero"`
+	} `inj:"db,prefix"`
+}
+
+func validatedValues() map[string]interface{} {
+
+	return map[string]interface{}{
+		"server.port": 8080,
+		"log.level":   "info",
+		"app.name":    "inj",
+		"app.url":     "http://localhost",
+		"app.timeout": "30s",
+		"app.tags":    "one,two",
+		"db.host":     "localhost",
+	}
+}
+
+// Values that satisfy the rules should be assigned as usual
+func Test_ValidationRulesPass(t *testing.T) {
+
+	c := &validatedConfig{}
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(validatedValues()))
+	g.Provide(c)
+
+	assertNoGraphErrors(t, g)
+
+	if c.Port != 8080 || c.Level != "info" || c.Timeout != 30*time.Second || len(c.Tags) != 2 {
+		t.Errorf("Unexpected values %+v", c)
+	}
+}
+
+// Each broken rule should be reported with the struct path
+func Test_ValidationRulesFail(t *testing.T) {
+
+	for path, c := range map[string]struct {
+		value    interface{}
+		expected string
+	}{
+		"server.port": {0, ".Port: Must be at least 1"},
+		"log.level":   {"trace", ".Level: Must be one of debug, info, warn"},
+		"app.name":    {"Inj", ".Name: Must match ^[a-z]+$"},
+		"app.url":     {"", ".URL: Must not be empty"},
+		"app.timeout": {"2m", ".Timeout: Must be at most 1m"},
+		"app.tags":    {[]string{}, ".Tags: Length must be at least 1"},
+		"db.host":     {"", ".DB.Host: Must not be empty"},
+	} {
+		values := validatedValues()
+		values[path] = c.value
+
+		g := newGraph()
+		g.AddDatasource(NewMockDatasourceReader(values))
+		g.Provide(&validatedConfig{})
+
+		valid, errs := g.Assert()
+
+		if valid || len(errs) != 1 {
+			t.Errorf("%s: expected one error, got %v", path, errs)
+			continue
+		}
+
+		if !strings.HasSuffix(errs[0], c.expected) {
+			t.Errorf("%s: expected an error ending %q, got %q", path, c.expected, errs[0])
+		}
+	}
+}
+
+// Parsing rules from tags
+func Test_ParseValidationRules(t *testing.T) {
+
+	d := parseStructTag(`inj:"a,min=1,b,oneof=x|y,nonzero,other=1"`)
+
+	if g, e := strings.Join(d.DatasourcePaths, " "), "a b"; g != e {
+		t.Errorf("Expected paths %s, got %s", e, g)
+	}
+
+	if g, e := len(d.Rules), 3; g != e {
+		t.Errorf("Expected %d rules, got %d", e, g)
+	}
+
+	if d.TagError == nil || d.TagError.Error() != "Unknown rule other=1" {
+		t.Errorf("Expected an unknown rule error, got %v", d.TagError)
+	}
+
+	// Regular expressions take up the rest of the tag
+	d = parseStructTag(`inj:"code,nonzero,regexp=^[a-z]{2,5}$"`)
+
+	if len(d.DatasourcePaths) != 1 || len(d.Rules) != 2 || d.Rules[1].arg != "^[a-z]{2,5}$" || d.TagError != nil {
+		t.Errorf("Unexpected dependency %+v", d)
+	}
+}
+
+// Malformed rules should be reported by Assert(), and the fields left alone
+func Test_MalformedValidationRules(t *testing.T) {
+
+	type config struct {
+		Code   string        `inj:"code,regexp=^[a-z]{2,5}$"`
+		Typo   int           `inj:"port,mx=5"`
+		Regexp string        `inj:"code,regexp=(["`
+		Bound  time.Duration `inj:"timeout,max=lots"`
+		Type   bool          `inj:"debug,min=1"`
+		Oneof  string        `inj:"code,oneof="`
+		DB     struct {
+			Host string
+		} `inj:"db,prefix,mx=1"`
+	}
+
+	c := &config{}
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{
+		"code":    "abc",
+		"port":    1,
+		"timeout": "1s",
+		"debug":   true,
+		"db.host": "localhost",
+	}))
+	g.Provide(c)
+
+	_, errs := g.Assert()
+	joined := strings.Join(errs, "\n")
+
+	for _, expected := range []string{
+		".Typo: Unknown rule mx=5",
+		".Regexp: Invalid regexp rule ([: error parsing regexp",
+		".Bound: Invalid max rule lots",
+		".Type: Rule min can't be applied to bool",
+		".Oneof: Rule oneof has no options",
+		".DB.Host: Unknown rule mx=1",
+	} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("Expected an error containing %q, got %v", expected, errs)
+		}
+	}
+
+	if len(errs) != 6 || c.Code != "abc" || c.Typo != 0 || c.DB.Host != "" {
+		t.Errorf("Unexpected errors %v or values %+v", errs, c)
+	}
+}
+
+// A type that validates itself
+type selfValidator struct {
+	Min int `inj:"min"`
+	Max int `inj:"max"`
+}
+
+func (s *selfValidator) Validate() error {
+
+	if s.Min > s.Max {
+		return errors.New("Min is greater than max")
+	}
+
+	return nil
+}
+
+// Validate() should be called after the graph is connected
+func Test_Validator(t *testing.T) {
+
+	g := newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"min": 2, "max": 1}))
+	g.Provide(&selfValidator{})
+
+	valid, errs := g.Assert()
+
+	if valid || len(errs) != 1 || errs[0] != "*inj.selfValidator: Min is greater than max" {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	g = newGraph()
+	g.AddDatasource(NewMockDatasourceReader(map[string]interface{}{"min": 1, "max": 2}))
+	g.Provide(&selfValidator{})
+
+	assertNoGraphErrors(t, g)
+}